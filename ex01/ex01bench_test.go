@@ -0,0 +1,191 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/dialect"
+)
+
+// ベンチマークはdocker-compose.ymlの接続先（環境変数とコマンドライン引数で上書き可）に接続できる場合だけ実行する
+// サブベンチマークの名前は key=value の形式にしてbenchstatで集計できるようにする
+
+var benchBatches = []int{1, 10, 100}
+
+// benchDB は接続してテーブルを初期化する。接続できない場合はスキップする
+func benchDB(b *testing.B, driverName string) (*sql.DB, dialect.Dialect) {
+	b.Helper()
+	if testing.Short() {
+		b.Skip("skipping in short mode")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var db *sql.DB
+	var err error
+	if driverName == dbsetup.DriverMySQL {
+		db, err = setupMySQL(ctx)
+	} else {
+		db, err = setupPg(ctx, driverName)
+	}
+	if err != nil {
+		b.Skipf("%s: database is not available: %v", driverName, err)
+	}
+	b.Cleanup(func() { db.Close() })
+	d, err := dialect.For(driverName)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return db, d
+}
+
+// movieInsertQuery はn件分のVALUESを持つINSERT。RETURNINGに対応している場合は主キーを返す
+func movieInsertQuery(d dialect.Dialect, n int) string {
+	query := "INSERT INTO movie (title, created_at, updated_at) VALUES " + strings.Repeat("(?, ?, ?), ", n-1) + "(?, ?, ?)"
+	if d.Supports(dialect.Returning) {
+		query += " RETURNING id"
+	}
+	return d.Rebind(query)
+}
+
+// inserter はトランザクション内でn件をINSERTし、採番された主キーを返す
+type inserter func(ctx context.Context, tx *sql.Tx, n int, at time.Time) ([]int64, error)
+
+// execInsert はqueryを実行する。stmtがnilでない場合はプリペアドステートメントを使う
+// PostgreSQLはRETURNING（Ex01Pg03、Ex01Pg05）、MySQLはLastInsertId（Ex01MySQL01、Ex01MySQL03）で主キーを取得する
+func execInsert(ctx context.Context, tx *sql.Tx, d dialect.Dialect, query string, stmt *sql.Stmt, args []any, n int) ([]int64, error) {
+	if stmt != nil {
+		stmt = tx.StmtContext(ctx, stmt)
+	}
+	if d.Supports(dialect.Returning) {
+		var rows *sql.Rows
+		var err error
+		if stmt != nil {
+			rows, err = stmt.QueryContext(ctx, args...)
+		} else {
+			rows, err = tx.QueryContext(ctx, query, args...)
+		}
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+		ids := make([]int64, 0, n)
+		for rows.Next() {
+			var id int64
+			if err := rows.Scan(&id); err != nil {
+				return nil, err
+			}
+			ids = append(ids, id)
+		}
+		return ids, rows.Err()
+	}
+
+	var result sql.Result
+	var err error
+	if stmt != nil {
+		result, err = stmt.ExecContext(ctx, args...)
+	} else {
+		result, err = tx.ExecContext(ctx, query, args...)
+	}
+	if err != nil {
+		return nil, err
+	}
+	first, err := result.LastInsertId()
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]int64, n)
+	for i := range ids {
+		ids[i] = first + int64(i) // auto_increment_incrementは1
+	}
+	return ids, nil
+}
+
+// newInserter は1件ずつ（single）か複数レコードをまとめて（multi）INSERTするinserterを返す
+// preparedの場合はプリペアドステートメントをベンチマークの前に作成しておく
+func newInserter(b *testing.B, db *sql.DB, d dialect.Dialect, multi, prepared bool, batch int) inserter {
+	b.Helper()
+	rowsPerStmt := 1
+	if multi {
+		rowsPerStmt = batch
+	}
+	query := movieInsertQuery(d, rowsPerStmt)
+	var stmt *sql.Stmt
+	if prepared {
+		var err error
+		if stmt, err = db.PrepareContext(context.Background(), query); err != nil {
+			b.Fatal(err)
+		}
+		b.Cleanup(func() { stmt.Close() })
+	}
+	return func(ctx context.Context, tx *sql.Tx, n int, at time.Time) ([]int64, error) {
+		ids := make([]int64, 0, n)
+		for i := 0; i < n; i += rowsPerStmt {
+			args := make([]any, 0, rowsPerStmt*3)
+			for j := range rowsPerStmt {
+				args = append(args, fmt.Sprintf("タイトル%05d", i+j+1), at, at)
+			}
+			chunk, err := execInsert(ctx, tx, d, query, stmt, args, rowsPerStmt)
+			if err != nil {
+				return nil, err
+			}
+			ids = append(ids, chunk...)
+		}
+		return ids, nil
+	}
+}
+
+// BenchmarkInsertDelete はbatch件のINSERTとIN句のリストによるDELETEを1つのトランザクションで実行する
+func BenchmarkInsertDelete(b *testing.B) {
+	for _, driverName := range []string{dbsetup.DriverPgx, dbsetup.DriverPq, dbsetup.DriverMySQL} {
+		b.Run("driver="+driverName, func(b *testing.B) {
+			db, d := benchDB(b, driverName)
+			for _, insert := range []string{"single", "multi"} {
+				for _, prepared := range []bool{false, true} {
+					for _, batch := range benchBatches {
+						name := fmt.Sprintf("insert=%s/prepared=%t/batch=%d", insert, prepared, batch)
+						b.Run(name, func(b *testing.B) {
+							ins := newInserter(b, db, d, insert == "multi", prepared, batch)
+							benchInsertDelete(b, db, d, ins, batch)
+						})
+					}
+				}
+			}
+		})
+	}
+}
+
+func benchInsertDelete(b *testing.B, db *sql.DB, d dialect.Dialect, ins inserter, batch int) {
+	ctx := context.Background()
+	at := time.Now()
+	n := 0
+	for b.Loop() {
+		n++
+		tx, err := db.BeginTx(ctx, nil)
+		if err != nil {
+			b.Fatal(err)
+		}
+		ids, err := ins(ctx, tx, batch, at)
+		if err != nil {
+			tx.Rollback()
+			b.Fatal(err)
+		}
+		rowsAffected, err := dialect.ExecIn(ctx, tx, d, "DELETE FROM movie WHERE id IN (?...)", ids)
+		if err != nil {
+			tx.Rollback()
+			b.Fatal(err)
+		}
+		if rowsAffected != int64(batch) {
+			tx.Rollback()
+			b.Fatalf("deleted %d rows, want %d", rowsAffected, batch)
+		}
+		if err := tx.Commit(); err != nil {
+			b.Fatal(err)
+		}
+	}
+	b.ReportMetric(float64(batch*n)/b.Elapsed().Seconds(), "rows/s")
+}
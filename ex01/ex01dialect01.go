@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/rowscan"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex01Dialect01",
+		Target:      runner.Either,
+		Description: "Dialectを使って同じコードをPostgreSQLとMySQLで実行する",
+		Run:         runner.Portable(Ex01Dialect01),
+	})
+}
+
+func Ex01Dialect01(ctx context.Context, db *sql.DB, d dialect.Dialect) error {
+	// トランザクション開始
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); !errors.Is(err, sql.ErrTxDone) {
+			slog.WarnContext(ctx, "Rollback", "err", err)
+		}
+	}()
+
+	// INSERT
+	now := time.Now()
+	insert := d.Rebind("INSERT INTO movie (title, created_at, updated_at) VALUES (?, ?, ?)")
+	ids := []any{} // ExecContextに渡すためint64ではなくanyにしておく
+	for _, title := range []string{"タイトルA", "タイトルB", "タイトルC"} {
+		var id int64
+		if d.Supports(dialect.Returning) {
+			// RETURNINGで採番された主キーを取得する
+			if err = tx.QueryRowContext(ctx, insert+" RETURNING id", title, now, now).Scan(&id); err != nil {
+				return err
+			}
+		} else {
+			// LastInsertIdで採番された主キーを取得する
+			result, err := tx.ExecContext(ctx, insert, title, now, now)
+			if err != nil {
+				return err
+			}
+			if id, err = result.LastInsertId(); err != nil {
+				return err
+			}
+		}
+		ids = append(ids, id)
+	}
+	slog.InfoContext(ctx, "INSERT", "dialect", d.Name(), "ids", ids)
+
+	// SELECT
+	rows, err := tx.QueryContext(ctx,
+		"SELECT id, title, created_at, updated_at FROM movie WHERE id IN "+d.In(1, len(ids))+" ORDER BY id",
+		ids...,
+	)
+	if err != nil {
+		return err
+	}
+	for movie, err := range rowscan.All[Movie](rows) {
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "SELECT", "id", movie.ID, "title", movie.Title, "created_at", movie.CreatedAt, "updated_at", movie.UpdatedAt)
+	}
+
+	// DELETE
+	result, err := tx.ExecContext(ctx,
+		"DELETE FROM movie WHERE id IN "+d.In(1, len(ids)),
+		ids...,
+	)
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := result.RowsAffected()
+	slog.InfoContext(ctx, "DELETE", "rowsAffected", rowsAffected)
+
+	// コミット
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/rowscan"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex01Dialect02",
+		Target:      runner.Either,
+		Description: "複数レコードをINSERTして、INSERTしたレコードを取得する（MySQLはRETURNINGをエミュレート）",
+		Run:         runner.Portable(Ex01Dialect02),
+	})
+}
+
+var movieInsert = dialect.BulkInsert{
+	Table:     "movie",
+	Key:       "id",
+	Columns:   []string{"title", "created_at", "updated_at"},
+	Returning: []string{"id", "title", "created_at", "updated_at"},
+}
+
+func Ex01Dialect02(ctx context.Context, db *sql.DB, d dialect.Dialect) error {
+	// トランザクション開始
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); !errors.Is(err, sql.ErrTxDone) {
+			slog.WarnContext(ctx, "Rollback", "err", err)
+		}
+	}()
+
+	// INSERT
+	now := time.Now()
+	ids := []int32{}
+	if err = movieInsert.Exec(ctx, tx, d,
+		[][]any{
+			{"タイトルA", now, now},
+			{"タイトルB", now, now},
+			{"タイトルC", now, now},
+		},
+		func(rows *sql.Rows) error {
+			movie, err := rowscan.One[Movie](rows)
+			if err != nil {
+				return err
+			}
+			slog.InfoContext(ctx, "INSERT", "id", movie.ID, "title", movie.Title, "created_at", movie.CreatedAt, "updated_at", movie.UpdatedAt)
+			ids = append(ids, movie.ID)
+			return nil
+		},
+	); err != nil {
+		return err
+	}
+
+	// DELETE
+	rowsAffected, err := dialect.ExecIn(ctx, tx, d, "DELETE FROM movie WHERE id IN (?...)", ids)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "DELETE", "rowsAffected", rowsAffected)
+
+	// コミット
+	if err = tx.Commit(); err != nil {
+		return err
+	}
+
+	return nil
+}
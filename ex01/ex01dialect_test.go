@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/fakedb"
+	"github.com/ystkg/db-examples/internal/golden"
+)
+
+func TestEx01Dialect01(t *testing.T) {
+	for _, tt := range []struct {
+		dialect dialect.Dialect
+		script  func(s *fakedb.Script)
+		want    []string
+	}{
+		{
+			dialect: dialect.Postgres,
+			script: func(s *fakedb.Script) {
+				for id := range int64(3) {
+					s.Query("RETURNING id").Rows([]string{"id"}, []driver.Value{id + 1}).Once()
+				}
+			},
+			want: []string{
+				"Begin Serializable",
+				"Query INSERT INTO movie (title, created_at, updated_at) VALUES ($1, $2, $3) RETURNING id",
+				"Query INSERT INTO movie (title, created_at, updated_at) VALUES ($1, $2, $3) RETURNING id",
+				"Query INSERT INTO movie (title, created_at, updated_at) VALUES ($1, $2, $3) RETURNING id",
+				"Query SELECT id, title, created_at, updated_at FROM movie WHERE id IN ($1,$2,$3) ORDER BY id",
+				"Exec DELETE FROM movie WHERE id IN ($1,$2,$3)",
+				"Commit",
+			},
+		},
+		{
+			dialect: dialect.MySQL,
+			script: func(s *fakedb.Script) {
+				for id := range int64(3) {
+					s.Exec("INSERT INTO movie").Result(id+1, 1).Once()
+				}
+			},
+			want: []string{
+				"Begin Serializable",
+				"Exec INSERT INTO movie (title, created_at, updated_at) VALUES (?, ?, ?)",
+				"Exec INSERT INTO movie (title, created_at, updated_at) VALUES (?, ?, ?)",
+				"Exec INSERT INTO movie (title, created_at, updated_at) VALUES (?, ?, ?)",
+				"Query SELECT id, title, created_at, updated_at FROM movie WHERE id IN (?,?,?) ORDER BY id",
+				"Exec DELETE FROM movie WHERE id IN (?,?,?)",
+				"Commit",
+			},
+		},
+	} {
+		t.Run(tt.dialect.Name(), func(t *testing.T) {
+			s := fakedb.NewScript()
+			tt.script(s)
+			s.Query("SELECT id, title").Rows(movieColumns,
+				movieRow(1, "タイトルA"), movieRow(2, "タイトルB"), movieRow(3, "タイトルC"),
+			)
+			s.Exec("DELETE FROM movie").Result(0, 3)
+			db := fakedb.Open(s)
+			defer db.Close()
+			out := golden.Capture(t)
+
+			if err := Ex01Dialect01(context.Background(), db, tt.dialect); err != nil {
+				t.Fatal(err)
+			}
+			out.Assert(t)
+
+			checkTrace(t, s, tt.want)
+			calls := s.Calls()
+			if del := calls[len(calls)-2]; len(del.Args) != 3 || del.Args[0] != int64(1) {
+				t.Errorf("DELETE args = %v", del.Args)
+			}
+		})
+	}
+}
+
+func TestEx01Dialect02(t *testing.T) {
+	for _, tt := range []struct {
+		dialect dialect.Dialect
+		script  func(s *fakedb.Script)
+		want    []string
+	}{
+		{
+			dialect: dialect.Postgres,
+			script: func(s *fakedb.Script) {
+				s.Query("RETURNING").Rows(movieColumns,
+					movieRow(1, "タイトルA"), movieRow(2, "タイトルB"), movieRow(3, "タイトルC"),
+				)
+			},
+			want: []string{
+				"Begin Serializable",
+				`Query INSERT INTO "movie" ("title", "created_at", "updated_at") VALUES ($1, $2, $3), ($4, $5, $6), ($7, $8, $9) RETURNING "id", "title", "created_at", "updated_at"`,
+				"Exec DELETE FROM movie WHERE id IN ($1,$2,$3)",
+				"Commit",
+			},
+		},
+		{
+			dialect: dialect.MySQL,
+			script: func(s *fakedb.Script) {
+				s.Exec("INSERT INTO `movie`").Result(1, 3)
+				s.Query("@@auto_increment_increment").Rows([]string{"@@auto_increment_increment"}, []driver.Value{int64(1)})
+				s.Query("SELECT `title`, `created_at`, `updated_at`").Rows(movieColumns[1:],
+					movieRow(1, "タイトルA")[1:], movieRow(2, "タイトルB")[1:], movieRow(3, "タイトルC")[1:],
+				)
+				s.Query("SELECT `id`, `title`").Rows(movieColumns,
+					movieRow(1, "タイトルA"), movieRow(2, "タイトルB"), movieRow(3, "タイトルC"),
+				)
+			},
+			want: []string{
+				"Begin Serializable",
+				"Exec INSERT INTO `movie` (`title`, `created_at`, `updated_at`) VALUES (?, ?, ?), (?, ?, ?), (?, ?, ?)",
+				"Query SELECT @@auto_increment_increment",
+				"Query SELECT `title`, `created_at`, `updated_at` FROM `movie` WHERE `id` IN (?,?,?) ORDER BY `id`",
+				"Query SELECT `id`, `title`, `created_at`, `updated_at` FROM `movie` WHERE `id` IN (?,?,?) ORDER BY `id`",
+				"Exec DELETE FROM movie WHERE id IN (?,?,?)",
+				"Commit",
+			},
+		},
+	} {
+		t.Run(tt.dialect.Name(), func(t *testing.T) {
+			s := fakedb.NewScript()
+			tt.script(s)
+			s.Exec("DELETE FROM movie").Result(0, 3)
+			db := fakedb.Open(s)
+			defer db.Close()
+			out := golden.Capture(t)
+
+			if err := Ex01Dialect02(context.Background(), db, tt.dialect); err != nil {
+				t.Fatal(err)
+			}
+			out.Assert(t)
+
+			checkTrace(t, s, tt.want)
+		})
+	}
+}
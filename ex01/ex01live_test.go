@@ -0,0 +1,13 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/ystkg/db-examples/internal/golden"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+// TestLive はgo test -live -run TestLive の場合だけ、docker-compose.ymlのデータベースで実行する
+func TestLive(t *testing.T) {
+	golden.Live(t, runner.Setup{Pg: setupPg, MySQL: setupMySQL})
+}
@@ -6,8 +6,20 @@ import (
 	"errors"
 	"log/slog"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/rowscan"
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex01MySQL01",
+		Target:      runner.MySQL,
+		Description: "1レコードINSERTしたあと、SELECTして、最後にDELETEする",
+		Run:         runner.Single(Ex01MySQL01),
+	})
+}
+
 func Ex01MySQL01(ctx context.Context, db *sql.DB) error {
 	// トランザクション開始
 	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
@@ -30,22 +42,18 @@ func Ex01MySQL01(ctx context.Context, db *sql.DB) error {
 	}
 
 	// SELECT
-	var id int32
-	var title string
-	var createdAt, updatedAt time.Time
-	if err = tx.QueryRowContext(ctx,
+	movie, err := rowscan.Row[Movie](tx.QueryRowContext(ctx,
 		"SELECT id, title, created_at, updated_at FROM movie ORDER BY id DESC LIMIT 1",
-	).Scan(
-		&id, &title, &createdAt, &updatedAt,
-	); err != nil {
+	), movieColumns...)
+	if err != nil {
 		return err
 	}
-	slog.InfoContext(ctx, "SELECT", "id", id, "title", title, "created_at", createdAt, "updated_at", updatedAt)
+	slog.InfoContext(ctx, "SELECT", "id", movie.ID, "title", movie.Title, "created_at", movie.CreatedAt, "updated_at", movie.UpdatedAt)
 
 	// DELETE
 	if _, err = tx.ExecContext(ctx,
 		"DELETE FROM movie WHERE id = ?",
-		id,
+		movie.ID,
 	); err != nil {
 		return err
 	}
@@ -6,8 +6,20 @@ import (
 	"errors"
 	"log/slog"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/rowscan"
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex01MySQL02",
+		Target:      runner.MySQL,
+		Description: "LastInsertIdでデータベース側で採番されたidを取得する",
+		Run:         runner.Single(Ex01MySQL02),
+	})
+}
+
 func Ex01MySQL02(ctx context.Context, db *sql.DB) error {
 	// トランザクション開始
 	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
@@ -37,18 +49,14 @@ func Ex01MySQL02(ctx context.Context, db *sql.DB) error {
 	slog.InfoContext(ctx, "INSERT", "lastInsertId", lastInsertId, "rowsAffected", rowsAffected)
 
 	// SELECT
-	var id int32
-	var title string
-	var createdAt, updatedAt time.Time
-	if err = tx.QueryRowContext(ctx,
+	movie, err := rowscan.Row[Movie](tx.QueryRowContext(ctx,
 		"SELECT id, title, created_at, updated_at FROM movie WHERE id = ?",
 		lastInsertId,
-	).Scan(
-		&id, &title, &createdAt, &updatedAt,
-	); err != nil {
+	), movieColumns...)
+	if err != nil {
 		return err
 	}
-	slog.InfoContext(ctx, "SELECT", "id", id, "title", title, "created_at", createdAt, "updated_at", updatedAt)
+	slog.InfoContext(ctx, "SELECT", "id", movie.ID, "title", movie.Title, "created_at", movie.CreatedAt, "updated_at", movie.UpdatedAt)
 
 	// コミット
 	if err = tx.Commit(); err != nil {
@@ -4,12 +4,23 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"log/slog"
-	"strings"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/rowscan"
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex01MySQL03",
+		Target:      runner.MySQL,
+		Description: "複数レコードをINSERTしてLastInsertIdとRowsAffectedを確認する",
+		Run:         runner.Single(Ex01MySQL03),
+	})
+}
+
 func Ex01MySQL03(ctx context.Context, db *sql.DB) error {
 	// トランザクション開始
 	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
@@ -48,34 +59,22 @@ func Ex01MySQL03(ctx context.Context, db *sql.DB) error {
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			slog.WarnContext(ctx, "Close", "err", err)
-		}
-	}()
 
-	ids := []any{} // ExecContextに渡すためint32ではなくanyにしておく
-	for rows.Next() {
-		var id int32
-		var title string
-		var createdAt, updatedAt time.Time
-		if err = rows.Scan(&id, &title, &createdAt, &updatedAt); err != nil {
+	ids := []int32{}
+	for movie, err := range rowscan.All[Movie](rows) {
+		if err != nil {
 			return err
 		}
-		slog.InfoContext(ctx, "SELECT", "id", id, "title", title, "created_at", createdAt, "updated_at", updatedAt)
-		ids = append(ids, id)
+		slog.InfoContext(ctx, "SELECT", "id", movie.ID, "title", movie.Title, "created_at", movie.CreatedAt, "updated_at", movie.UpdatedAt)
+		ids = append(ids, movie.ID)
 	}
 
 	// DELETE
-	ph := strings.Repeat(",?", len(ids)) // プレースホルダ
-	result, err = tx.ExecContext(ctx,
-		fmt.Sprintf("DELETE FROM movie WHERE id IN (%s)", ph[1:]),
-		ids...,
-	)
+	// IN句のリストはパラメータ数の上限を超える場合に分割して実行される
+	rowsAffected, err = dialect.ExecIn(ctx, tx, dialect.MySQL, "DELETE FROM movie WHERE id IN (?...)", ids)
 	if err != nil {
 		return err
 	}
-	rowsAffected, _ = result.RowsAffected()
 	slog.InfoContext(ctx, "DELETE", "rowsAffected", rowsAffected)
 
 	// コミット
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"iter"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/mysqlload"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex01MySQL04",
+		Target:      runner.MySQL,
+		Description: "LOAD DATA LOCAL INFILEでio.Readerから大量レコードを登録する",
+		Run:         runner.Single(Ex01MySQL04),
+	})
+}
+
+var movieLoadData = mysqlload.Loader{
+	Table:   "movie",
+	Columns: []string{"title", "created_at", "updated_at"},
+}
+
+// movieSeq はn件のMovieを順に生成する
+func movieSeq(n int, at time.Time) iter.Seq[Movie] {
+	return func(yield func(Movie) bool) {
+		for i := range n {
+			if !yield(Movie{Title: fmt.Sprintf("タイトル%05d", i+1), CreatedAt: at, UpdatedAt: at}) {
+				return
+			}
+		}
+	}
+}
+
+func Ex01MySQL04(ctx context.Context, db *sql.DB) error {
+	loc, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return err
+	}
+
+	// イテレータから登録
+	r := mysqlload.Encode(movieSeq(bulkRows, time.Now()), loc, func(m Movie) []any {
+		return []any{m.Title, m.CreatedAt, m.UpdatedAt}
+	})
+	result, err := movieLoadData.Load(ctx, db, r)
+	if errors.Is(err, mysqlload.ErrDisabled) {
+		// 登録できていないので成功扱いにはしない
+		slog.WarnContext(ctx, "LOAD DATA", "err", err, "hint", "SET PERSIST local_infile = ON")
+		return err
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "LOAD DATA", "rows", result.Rows, "warnings", len(result.Warnings))
+
+	// io.Readerから登録。日時が不正な行はエラーにならず警告になる
+	tsv := "タイトルX\t2024-10-05 10:00:00\t2024-10-05 10:00:00\n" +
+		"タイトルY\tnot a datetime\t2024-10-05 10:00:00\n"
+	result, err = movieLoadData.Load(ctx, db, strings.NewReader(tsv))
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "LOAD DATA", "rows", result.Rows, "warnings", len(result.Warnings))
+	for _, w := range result.Warnings {
+		slog.WarnContext(ctx, "SHOW WARNINGS", "Level", w.Level, "Code", w.Code, "Message", w.Message)
+	}
+
+	// DELETE
+	deleted, err := db.ExecContext(ctx, "DELETE FROM movie")
+	if err != nil {
+		return err
+	}
+	rowsAffected, _ := deleted.RowsAffected()
+	slog.InfoContext(ctx, "DELETE", "rowsAffected", rowsAffected)
+
+	return nil
+}
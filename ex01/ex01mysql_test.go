@@ -0,0 +1,133 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ystkg/db-examples/internal/fakedb"
+	"github.com/ystkg/db-examples/internal/golden"
+	"github.com/ystkg/db-examples/internal/mysqlload"
+)
+
+func TestEx01MySQL01(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("SELECT id, title").Rows(movieColumns, movieRow(100, "タイトルA"))
+	db := fakedb.Open(s)
+	defer db.Close()
+	out := golden.Capture(t)
+
+	if err := Ex01MySQL01(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	out.Assert(t)
+
+	checkTrace(t, s, []string{
+		"Begin Serializable",
+		"Exec INSERT INTO movie (title, created_at, updated_at) VALUES (?, ?, ?)",
+		"Query SELECT id, title, created_at, updated_at FROM movie ORDER BY id DESC LIMIT 1",
+		"Exec DELETE FROM movie WHERE id = ?",
+		"Commit",
+	})
+}
+
+func TestEx01MySQL02(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Exec("INSERT INTO movie").Result(100, 1)
+	s.Query("SELECT id, title").Rows(movieColumns, movieRow(100, "タイトルA"))
+	db := fakedb.Open(s)
+	defer db.Close()
+	out := golden.Capture(t)
+
+	if err := Ex01MySQL02(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	out.Assert(t)
+
+	checkTrace(t, s, []string{
+		"Begin Serializable",
+		"Exec INSERT INTO movie (title, created_at, updated_at) VALUES (?, ?, ?)",
+		"Query SELECT id, title, created_at, updated_at FROM movie WHERE id = ?",
+		"Commit",
+	})
+	if sel := s.Calls()[3]; len(sel.Args) != 1 || sel.Args[0] != int64(100) {
+		t.Errorf("SELECT args = %v", sel.Args)
+	}
+}
+
+func TestEx01MySQL03(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Exec("INSERT INTO movie").Result(100, 3)
+	s.Query("SELECT id, title").Rows(movieColumns,
+		movieRow(102, "タイトルC"), movieRow(101, "タイトルB"), movieRow(100, "タイトルA"),
+	)
+	s.Exec("DELETE").Result(0, 3)
+	db := fakedb.Open(s)
+	defer db.Close()
+	out := golden.Capture(t)
+
+	if err := Ex01MySQL03(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	out.Assert(t)
+
+	checkTrace(t, s, []string{
+		"Begin Serializable",
+		"Exec INSERT INTO movie (title, created_at, updated_at) VALUES (?, ?, ?), (?, ?, ?), (?, ?, ?)",
+		"Query SELECT id, title, created_at, updated_at FROM movie ORDER BY id DESC LIMIT ?",
+		"Exec DELETE FROM movie WHERE id IN (?,?,?)",
+		"Commit",
+	})
+}
+
+func TestEx01MySQL04(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Exec("LOAD DATA").Result(0, 5000).Once()
+	s.Exec("LOAD DATA").Result(0, 2)
+	s.Query("SHOW WARNINGS").Rows([]string{"Level", "Code", "Message"}).Once()
+	s.Query("SHOW WARNINGS").Rows([]string{"Level", "Code", "Message"},
+		[]driver.Value{"Warning", int64(1265), "Data truncated for column 'created_at' at row 2"},
+	)
+	s.Exec("DELETE FROM movie").Result(0, 5002)
+	db := fakedb.Open(s)
+	defer db.Close()
+	out := golden.Capture(t)
+
+	if err := Ex01MySQL04(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	out.Assert(t)
+
+	var trace []string
+	for _, c := range s.Trace() {
+		// Reader::の名前は実行毎に変わる
+		trace = append(trace, regexp.MustCompile(`Reader::\w+`).ReplaceAllString(c, "Reader::<name>"))
+	}
+	load := "Exec LOAD DATA LOCAL INFILE 'Reader::<name>' INTO TABLE movie CHARACTER SET utf8mb4 (title, created_at, updated_at)"
+	want := []string{load, "Query SHOW WARNINGS", load, "Query SHOW WARNINGS", "Exec DELETE FROM movie"}
+	if !slices.Equal(trace, want) {
+		t.Errorf("trace\n got: %q\nwant: %q", trace, want)
+	}
+}
+
+func TestEx01MySQL04Disabled(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Exec("LOAD DATA").Err(&mysql.MySQLError{Number: 3948, Message: "Loading local data is disabled; this must be enabled on both the client and server sides"})
+	db := fakedb.Open(s)
+	defer db.Close()
+	out := golden.Capture(t)
+
+	// サーバ側で無効な場合は警告を出力してエラーを返す
+	if err := Ex01MySQL04(context.Background(), db); !errors.Is(err, mysqlload.ErrDisabled) {
+		t.Fatalf("err = %v", err)
+	}
+	out.Assert(t)
+
+	if got := len(s.Trace()); got != 1 {
+		t.Errorf("calls = %d, want 1", got)
+	}
+}
@@ -6,8 +6,22 @@ import (
 	"errors"
 	"log/slog"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/rowscan"
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex01Pg01",
+		Target:      runner.Pg,
+		Description: "1レコードINSERTしたあと、SELECTして、最後にDELETEする",
+		Drivers:     []string{dbsetup.DriverPgx, dbsetup.DriverPq},
+		Run:         runner.Single(Ex01Pg01),
+	})
+}
+
 func Ex01Pg01(ctx context.Context, db *sql.DB) error {
 	// トランザクション開始
 	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
@@ -30,22 +44,18 @@ func Ex01Pg01(ctx context.Context, db *sql.DB) error {
 	}
 
 	// SELECT
-	var id int32
-	var title string
-	var createdAt, updatedAt time.Time
-	if err = tx.QueryRowContext(ctx,
+	movie, err := rowscan.Row[Movie](tx.QueryRowContext(ctx,
 		"SELECT id, title, created_at, updated_at FROM movie ORDER BY id DESC LIMIT 1",
-	).Scan(
-		&id, &title, &createdAt, &updatedAt,
-	); err != nil {
+	), movieColumns...)
+	if err != nil {
 		return err
 	}
-	slog.InfoContext(ctx, "SELECT", "id", id, "title", title, "created_at", createdAt, "updated_at", updatedAt)
+	slog.InfoContext(ctx, "SELECT", "id", movie.ID, "title", movie.Title, "created_at", movie.CreatedAt, "updated_at", movie.UpdatedAt)
 
 	// DELETE
 	if _, err = tx.ExecContext(ctx,
 		"DELETE FROM movie WHERE id = $1",
-		id,
+		movie.ID,
 	); err != nil {
 		return err
 	}
@@ -6,8 +6,21 @@ import (
 	"errors"
 	"log/slog"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex01Pg02",
+		Target:      runner.Pg,
+		Description: "LastInsertIdがサポートされていないことを確認する",
+		Drivers:     []string{dbsetup.DriverPgx, dbsetup.DriverPq},
+		Run:         runner.Single(Ex01Pg02),
+	})
+}
+
 func Ex01Pg02(ctx context.Context, db *sql.DB) error {
 	// トランザクション開始
 	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
@@ -4,12 +4,25 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"log/slog"
-	"strings"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/rowscan"
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex01Pg04",
+		Target:      runner.Pg,
+		Description: "複数レコードのINSERTでRETURNINGとQueryContextを使う",
+		Drivers:     []string{dbsetup.DriverPgx, dbsetup.DriverPq},
+		Run:         runner.Single(Ex01Pg04),
+	})
+}
+
 func Ex01Pg04(ctx context.Context, db *sql.DB) error {
 	// トランザクション開始
 	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
@@ -33,16 +46,10 @@ func Ex01Pg04(ctx context.Context, db *sql.DB) error {
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			slog.WarnContext(ctx, "Close", "err", err)
-		}
-	}()
 
-	ids := []any{} // ExecContextに渡すためint32ではなくanyにしておく
-	for rows.Next() {
-		var id int32
-		if err = rows.Scan(&id); err != nil {
+	ids := []int32{}
+	for id, err := range rowscan.All[int32](rows) {
+		if err != nil {
 			return err
 		}
 		ids = append(ids, id)
@@ -50,18 +57,11 @@ func Ex01Pg04(ctx context.Context, db *sql.DB) error {
 	slog.InfoContext(ctx, "INSERT", "ids", ids)
 
 	// DELETE
-	ph := make([]string, len(ids))
-	for i := range ph {
-		ph[i] = fmt.Sprintf("$%d", i+1) // プレースホルダ
-	}
-	result, err := tx.ExecContext(ctx,
-		fmt.Sprintf("DELETE FROM movie WHERE id IN (%s)", strings.Join(ph, ",")),
-		ids...,
-	)
+	// IN句のリストはパラメータ数の上限を超える場合に分割して実行される
+	rowsAffected, err := dialect.ExecIn(ctx, tx, dialect.Postgres, "DELETE FROM movie WHERE id IN (?...)", ids)
 	if err != nil {
 		return err
 	}
-	rowsAffected, _ := result.RowsAffected()
 	slog.InfoContext(ctx, "DELETE", "rowsAffected", rowsAffected)
 
 	// コミット
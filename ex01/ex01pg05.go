@@ -4,12 +4,25 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"log/slog"
-	"strings"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/rowscan"
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex01Pg05",
+		Target:      runner.Pg,
+		Description: "RETURNINGで主キー以外のカラムも取得する",
+		Drivers:     []string{dbsetup.DriverPgx, dbsetup.DriverPq},
+		Run:         runner.Single(Ex01Pg05),
+	})
+}
+
 func Ex01Pg05(ctx context.Context, db *sql.DB) error {
 	// トランザクション開始
 	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
@@ -40,37 +53,22 @@ func Ex01Pg05(ctx context.Context, db *sql.DB) error {
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			slog.WarnContext(ctx, "Close", "err", err)
-		}
-	}()
 
-	ids := []any{} // ExecContextに渡すためint32ではなくanyにしておく
-	for rows.Next() {
-		var id int32
-		var title string
-		var createdAt, updatedAt time.Time
-		if err = rows.Scan(&id, &title, &createdAt, &updatedAt); err != nil {
+	ids := []int32{}
+	for movie, err := range rowscan.All[Movie](rows) {
+		if err != nil {
 			return err
 		}
-		slog.InfoContext(ctx, "INSERT", "id", id, "title", title, "created_at", createdAt, "updated_at", updatedAt)
-		ids = append(ids, id)
+		slog.InfoContext(ctx, "INSERT", "id", movie.ID, "title", movie.Title, "created_at", movie.CreatedAt, "updated_at", movie.UpdatedAt)
+		ids = append(ids, movie.ID)
 	}
 
 	// DELETE
-	ph := make([]string, len(ids))
-	for i := range ph {
-		ph[i] = fmt.Sprintf("$%d", i+1) // プレースホルダ
-	}
-	result, err := tx.ExecContext(ctx,
-		fmt.Sprintf("DELETE FROM movie WHERE id IN (%s)", strings.Join(ph, ",")),
-		ids...,
-	)
+	// IN句のリストはパラメータ数の上限を超える場合に分割して実行される
+	rowsAffected, err := dialect.ExecIn(ctx, tx, dialect.Postgres, "DELETE FROM movie WHERE id IN (?...)", ids)
 	if err != nil {
 		return err
 	}
-	rowsAffected, _ := result.RowsAffected()
 	slog.InfoContext(ctx, "DELETE", "rowsAffected", rowsAffected)
 
 	// コミット
@@ -4,12 +4,25 @@ import (
 	"context"
 	"database/sql"
 	"errors"
-	"fmt"
 	"log/slog"
-	"strings"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/rowscan"
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex01Pg06",
+		Target:      runner.Pg,
+		Description: "DELETEでRETURNINGを使う",
+		Drivers:     []string{dbsetup.DriverPgx, dbsetup.DriverPq},
+		Run:         runner.Single(Ex01Pg06),
+	})
+}
+
 func Ex01Pg06(ctx context.Context, db *sql.DB) error {
 	// トランザクション開始
 	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
@@ -33,16 +46,10 @@ func Ex01Pg06(ctx context.Context, db *sql.DB) error {
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			slog.WarnContext(ctx, "Close", "err", err)
-		}
-	}()
 
 	ids := []any{} // QueryContextに渡すためint32ではなくanyにしておく
-	for rows.Next() {
-		var id int32
-		if err = rows.Scan(&id); err != nil {
+	for id, err := range rowscan.All[int32](rows) {
+		if err != nil {
 			return err
 		}
 		ids = append(ids, id)
@@ -50,31 +57,19 @@ func Ex01Pg06(ctx context.Context, db *sql.DB) error {
 	slog.InfoContext(ctx, "INSERT", "ids", ids)
 
 	// DELETE
-	ph := make([]string, len(ids))
-	for i := range ph {
-		ph[i] = fmt.Sprintf("$%d", i+1) // プレースホルダ
-	}
 	rows, err = tx.QueryContext(ctx,
-		fmt.Sprintf("DELETE FROM movie WHERE id IN (%s) RETURNING id, title, created_at, updated_at", strings.Join(ph, ",")),
+		"DELETE FROM movie WHERE id IN "+dialect.Postgres.In(1, len(ids))+" RETURNING id, title, created_at, updated_at",
 		ids...,
 	)
 	if err != nil {
 		return err
 	}
-	defer func() {
-		if err := rows.Close(); err != nil {
-			slog.WarnContext(ctx, "Close", "err", err)
-		}
-	}()
 
-	for rows.Next() {
-		var id int32
-		var title string
-		var createdAt, updatedAt time.Time
-		if err = rows.Scan(&id, &title, &createdAt, &updatedAt); err != nil {
+	for movie, err := range rowscan.All[Movie](rows) {
+		if err != nil {
 			return err
 		}
-		slog.InfoContext(ctx, "DELETE", "id", id, "title", title, "created_at", createdAt, "updated_at", updatedAt)
+		slog.InfoContext(ctx, "DELETE", "id", movie.ID, "title", movie.Title, "created_at", movie.CreatedAt, "updated_at", movie.UpdatedAt)
 	}
 
 	// コミット
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/pgbulk"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex01Pg07",
+		Target:      runner.Pg,
+		Description: "大量レコードの登録で複数レコードのINSERT、COPY、pgx.Batchを比較する",
+		Drivers:     []string{dbsetup.DriverPgx, dbsetup.DriverPq},
+		Run:         runner.Single(Ex01Pg07),
+	})
+}
+
+var (
+	// bulkRows は登録するレコード数
+	bulkRows = 5000
+
+	// benchOut は比較結果の表の出力先
+	benchOut io.Writer = os.Stdout
+)
+
+var movieLoader = pgbulk.Loader{
+	Table:   "movie",
+	Key:     "id",
+	Columns: []string{"title", "created_at", "updated_at"},
+}
+
+func Ex01Pg07(ctx context.Context, db *sql.DB) error {
+	now := time.Now()
+	rows := make([][]any, bulkRows)
+	for i := range rows {
+		rows[i] = []any{fmt.Sprintf("タイトル%05d", i+1), now, now}
+	}
+
+	results := []pgbulk.Result{}
+	for _, m := range []pgbulk.Method{pgbulk.Insert, pgbulk.Copy, pgbulk.Batch} {
+		result, err := movieLoader.Load(ctx, db, m, rows)
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "LOAD", "method", m.String(), "used", result.Method.String(), "rows", result.Rows, "duration", result.Duration)
+		results = append(results, result)
+
+		// 次の方法の前に削除しておく
+		if _, err := db.ExecContext(ctx, "DELETE FROM movie"); err != nil {
+			return err
+		}
+	}
+
+	return pgbulk.WriteTable(benchOut, results)
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+	"github.com/ystkg/db-examples/internal/golden"
+)
+
+func movieRow(id int64, title string) []driver.Value {
+	at := time.Date(2025, 4, 1, 9, 0, 0, 0, time.UTC)
+	return []driver.Value{id, title, at, at}
+}
+
+func checkTrace(t *testing.T, s *fakedb.Script, want []string) {
+	t.Helper()
+	if got := s.Trace(); !slices.Equal(got, want) {
+		t.Errorf("trace\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestEx01Pg01(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("SELECT id, title").Rows(movieColumns, movieRow(1, "タイトルA"))
+	db := fakedb.Open(s)
+	defer db.Close()
+	out := golden.Capture(t)
+
+	if err := Ex01Pg01(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	out.Assert(t)
+
+	checkTrace(t, s, []string{
+		"Begin Serializable",
+		"Exec INSERT INTO movie (title, created_at, updated_at) VALUES ($1, $2, $3)",
+		"Query SELECT id, title, created_at, updated_at FROM movie ORDER BY id DESC LIMIT 1",
+		"Exec DELETE FROM movie WHERE id = $1",
+		"Commit",
+	})
+	calls := s.Calls()
+	if del := calls[len(calls)-2]; !slices.Equal(del.Args, []any{int32(1)}) {
+		t.Errorf("DELETE args = %v", del.Args)
+	}
+}
+
+func TestEx01Pg02(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Exec("INSERT INTO movie").Result(0, 1)
+	db := fakedb.Open(s)
+	defer db.Close()
+	out := golden.Capture(t)
+
+	if err := Ex01Pg02(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	out.Assert(t)
+
+	checkTrace(t, s, []string{
+		"Begin Serializable",
+		"Exec INSERT INTO movie (title, created_at, updated_at) VALUES ($1, $2, $3)",
+		"Commit",
+	})
+}
+
+func TestEx01Pg03(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("RETURNING id").Rows([]string{"id"}, []driver.Value{int64(7)})
+	s.Query("SELECT id, title").Rows(movieColumns, movieRow(7, "タイトルA"))
+	db := fakedb.Open(s)
+	defer db.Close()
+	out := golden.Capture(t)
+
+	if err := Ex01Pg03(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	out.Assert(t)
+
+	checkTrace(t, s, []string{
+		"Begin Serializable",
+		"Query INSERT INTO movie (title, created_at, updated_at) VALUES ($1, $2, $3) RETURNING id",
+		"Query SELECT id, title, created_at, updated_at FROM movie WHERE id = $1",
+		"Exec DELETE FROM movie WHERE id = $1",
+		"Commit",
+	})
+	calls := s.Calls()
+	if sel := calls[len(calls)-3]; !slices.Equal(sel.Args, []any{int32(7)}) {
+		t.Errorf("SELECT args = %v", sel.Args)
+	}
+}
+
+func TestEx01Pg04(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("RETURNING id").Rows([]string{"id"},
+		[]driver.Value{int64(1)}, []driver.Value{int64(2)}, []driver.Value{int64(3)},
+	)
+	s.Exec("DELETE").Result(0, 3)
+	db := fakedb.Open(s)
+	defer db.Close()
+	out := golden.Capture(t)
+
+	if err := Ex01Pg04(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	out.Assert(t)
+
+	checkTrace(t, s, []string{
+		"Begin Serializable",
+		"Query INSERT INTO movie (title, created_at, updated_at) VALUES ($1, $2, $3), ($4, $5, $6), ($7, $8, $9) RETURNING id",
+		"Exec DELETE FROM movie WHERE id IN ($1,$2,$3)",
+		"Commit",
+	})
+}
+
+func TestEx01Pg05(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("RETURNING id, title").Rows(movieColumns,
+		movieRow(1, "タイトルA"), movieRow(2, "タイトルB"), movieRow(3, "タイトルC"),
+	)
+	s.Exec("DELETE").Result(0, 3)
+	db := fakedb.Open(s)
+	defer db.Close()
+	out := golden.Capture(t)
+
+	if err := Ex01Pg05(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	out.Assert(t)
+
+	checkTrace(t, s, []string{
+		"Begin Serializable",
+		"Query INSERT INTO movie (title, created_at, updated_at) VALUES ($1, $2, $3), ($4, $5, $6), ($7, $8, $9) RETURNING id, title, created_at, updated_at",
+		"Exec DELETE FROM movie WHERE id IN ($1,$2,$3)",
+		"Commit",
+	})
+}
+
+func TestEx01Pg06(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("INSERT INTO movie").Rows([]string{"id"},
+		[]driver.Value{int64(1)}, []driver.Value{int64(2)}, []driver.Value{int64(3)},
+	)
+	s.Query("DELETE FROM movie").Rows(movieColumns,
+		movieRow(1, "タイトルA"), movieRow(2, "タイトルB"), movieRow(3, "タイトルC"),
+	)
+	db := fakedb.Open(s)
+	defer db.Close()
+	out := golden.Capture(t)
+
+	if err := Ex01Pg06(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	out.Assert(t)
+
+	checkTrace(t, s, []string{
+		"Begin Serializable",
+		"Query INSERT INTO movie (title, created_at, updated_at) VALUES ($1, $2, $3), ($4, $5, $6), ($7, $8, $9) RETURNING id",
+		"Query DELETE FROM movie WHERE id IN ($1,$2,$3) RETURNING id, title, created_at, updated_at",
+		"Commit",
+	})
+}
+
+func TestEx01Pg07(t *testing.T) {
+	rows, out := bulkRows, benchOut
+	t.Cleanup(func() { bulkRows, benchOut = rows, out })
+	bulkRows = 3
+	var table strings.Builder
+	benchOut = &table
+
+	s := fakedb.NewScript()
+	s.Query(`INSERT INTO "movie"`).Rows([]string{"id"}, []driver.Value{int64(1)}, []driver.Value{int64(2)}, []driver.Value{int64(3)})
+	db := fakedb.Open(s)
+	defer db.Close()
+	log := golden.Capture(t)
+
+	if err := Ex01Pg07(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	log.Assert(t)
+
+	// fakedbはpgxではないので、COPYとpgx.Batchも複数レコードのINSERTになる
+	insert := `Query INSERT INTO "movie" ("title", "created_at", "updated_at") VALUES ($1, $2, $3), ($4, $5, $6), ($7, $8, $9) RETURNING "id"`
+	load := []string{"Begin", insert, "Commit", "Exec DELETE FROM movie"}
+	checkTrace(t, s, slices.Concat(load, load, load))
+	if n := strings.Count(table.String(), "\n"); n != 4 {
+		t.Errorf("table has %d lines\n%s", n, table.String())
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/rowscan"
+	"github.com/ystkg/db-examples/internal/runner"
+	"github.com/ystkg/db-examples/internal/txretry"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex01Retry01",
+		Target:      runner.Either,
+		Description: "同時に実行したSERIALIZABLEのトランザクションの競合を再実行で解決する",
+		Run:         runner.Portable(Ex01Retry01),
+	})
+}
+
+func Ex01Retry01(ctx context.Context, db *sql.DB, d dialect.Dialect) error {
+	const workers = 8
+	var attempts atomic.Int64
+
+	var wg sync.WaitGroup
+	errs := make([]error, workers)
+	for i := range workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = txretry.Do(ctx, db,
+				&sql.TxOptions{Isolation: sql.LevelSerializable},
+				txretry.Policy{MaxAttempts: 20},
+				func(tx *sql.Tx) error {
+					attempts.Add(1)
+
+					// 件数を数えて次の連番のタイトルでINSERTする
+					// 同時に実行すると、PostgreSQLはシリアライズ失敗（40001）、MySQLはデッドロック（1213）になる
+					var count int
+					if err := tx.QueryRowContext(ctx, "SELECT COUNT(*) FROM movie").Scan(&count); err != nil {
+						return err
+					}
+					now := time.Now()
+					_, err := tx.ExecContext(ctx,
+						d.Rebind("INSERT INTO movie (title, created_at, updated_at) VALUES (?, ?, ?)"),
+						fmt.Sprintf("タイトル%d", count+1), now, now,
+					)
+					return err
+				},
+			)
+		}()
+	}
+	wg.Wait()
+	if err := errors.Join(errs...); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "INSERT", "workers", workers, "attempts", attempts.Load())
+
+	// SELECT（再実行によりタイトルは重複しない）
+	rows, err := db.QueryContext(ctx, "SELECT title FROM movie ORDER BY id")
+	if err != nil {
+		return err
+	}
+	titles, err := rowscan.Collect[string](rows)
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "SELECT", "titles", titles)
+
+	return nil
+}
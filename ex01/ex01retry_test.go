@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+func TestEx01Retry01(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("COUNT(*)").Rows([]string{"count"}, []driver.Value{int64(0)})
+	s.Commit().Err(&pgconn.PgError{Code: "40001"}).Times(3)
+	s.Query("SELECT title").Rows([]string{"title"}, []driver.Value{"タイトル1"})
+	db := fakedb.Open(s)
+	defer db.Close()
+
+	if err := Ex01Retry01(context.Background(), db, dialect.Postgres); err != nil {
+		t.Fatal(err)
+	}
+
+	// 3回のシリアライズ失敗は再実行される
+	count := map[fakedb.Op]int{}
+	for _, c := range s.Calls() {
+		count[c.Op]++
+	}
+	if count[fakedb.OpBegin] != 8+3 || count[fakedb.OpCommit] != 8+3 || count[fakedb.OpExec] != 8+3 {
+		t.Errorf("count = %v", count)
+	}
+}
@@ -4,19 +4,25 @@ import (
 	"context"
 	"database/sql"
 	_ "embed"
+	"flag"
 	"fmt"
 	"log"
-	"log/slog"
-	"os"
-	"strings"
-	"time"
-
-	"github.com/go-sql-driver/mysql"
-	_ "github.com/jackc/pgx/v5/stdlib"
-	_ "github.com/lib/pq"
-	"gopkg.in/yaml.v3"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+var (
+	printConfigFlag = flag.Bool("print-config", false, "接続設定を表示して終了する")
+	timeoutFlag     = flag.Duration("timeout", runner.DefaultTimeout, "サンプル1つあたりのタイムアウト")
+	pgFlags         = dbsetup.RegisterFlags(flag.CommandLine, "pg")
+	startupFlags    = dbsetup.RegisterStartupFlags(flag.CommandLine)
+	mysqlFlags      = dbsetup.RegisterFlags(flag.CommandLine, "mysql")
 )
 
+// timeZone はセッションのタイムゾーン
+const timeZone = "Asia/Tokyo"
+
 var (
 	//go:embed docker-compose.yml
 	yml []byte
@@ -34,155 +40,71 @@ var (
 	pgclean string
 )
 
-func setup(ctx context.Context, exname, pgDriver string) (*sql.DB, error) {
-	if len(exname) < 5 {
-		return nil, fmt.Errorf("unknown:%s", exname)
-	}
-	name := strings.ToUpper(exname[4:])
-	pgDriverName := "pgx"
-	if strings.EqualFold(pgDriver, "pq") {
-		pgDriverName = "postgres"
-	}
-	switch {
-	case strings.HasPrefix(name, "PG"):
-		return setupPg(ctx, pgDriverName)
-	case strings.HasPrefix(name, "MYSQL"):
-		return setupMySQL(ctx)
+func pgConfig(driverName string) (dbsetup.Config, error) {
+	conf, err := dbsetup.LoadPostgres(yml)
+	if err != nil {
+		return dbsetup.Config{}, err
 	}
-	return nil, fmt.Errorf("unknown:%s", exname)
-}
+	conf.Driver = driverName
+	conf.TimeZone = timeZone
 
-func setupPg(ctx context.Context, driverName string) (*sql.DB, error) {
-	conf := struct {
-		Services struct {
-			Postgres struct {
-				Ports       []string
-				Environment struct {
-					PostgresPassword string `yaml:"POSTGRES_PASSWORD"`
-				}
-			}
-		}
-	}{}
-	if err := yaml.Unmarshal(yml, &conf); err != nil {
-		return nil, err
-	}
+	return dbsetup.Resolve(conf, pgFlags)
+}
 
-	db, err := sql.Open(driverName,
-		fmt.Sprintf("postgres://postgres:%s@localhost:5432/postgres?sslmode=disable&TimeZone=Asia/Tokyo",
-			conf.Services.Postgres.Environment.PostgresPassword,
-		),
-	)
+func mysqlConfig() (dbsetup.Config, error) {
+	conf, err := dbsetup.LoadMySQL(yml)
 	if err != nil {
-		return nil, err
+		return dbsetup.Config{}, err
 	}
+	conf.TimeZone = timeZone
 
-	_, err = db.ExecContext(ctx, pgclean)
-	if err != nil {
-		return nil, err
-	}
+	return dbsetup.Resolve(conf, mysqlFlags)
+}
 
-	_, err = db.ExecContext(ctx, pgddl)
+func setupPg(ctx context.Context, driverName string) (*sql.DB, error) {
+	conf, err := pgConfig(driverName)
 	if err != nil {
 		return nil, err
 	}
 
-	return db, nil
+	return dbsetup.OpenReset(ctx, conf, pgclean, pgddl)
 }
 
 func setupMySQL(ctx context.Context) (*sql.DB, error) {
-	conf := struct {
-		Services struct {
-			Mysql struct {
-				Environment struct {
-					MysqlRootPassword string `yaml:"MYSQL_ROOT_PASSWORD"`
-					MysqlDatabase     string `yaml:"MYSQL_DATABASE"`
-				}
-			}
-		}
-	}{}
-	if err := yaml.Unmarshal(yml, &conf); err != nil {
-		return nil, err
-	}
-
-	loc, err := time.LoadLocation("Asia/Tokyo")
+	conf, err := mysqlConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	conn, err := mysql.NewConnector(&mysql.Config{
-		Addr:      "localhost:3306",
-		DBName:    conf.Services.Mysql.Environment.MysqlDatabase,
-		User:      "root",
-		Passwd:    conf.Services.Mysql.Environment.MysqlRootPassword,
-		ParseTime: true,
-		Loc:       loc,
-	})
-	if err != nil {
-		return nil, err
-	}
-	db := sql.OpenDB(conn)
+	return dbsetup.OpenReset(ctx, conf, mysqlclean, mysqlddl)
+}
 
-	_, err = db.ExecContext(ctx, mysqlclean)
+func printConfig() error {
+	pgConf, err := pgConfig(dbsetup.DriverPgx)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	_, err = db.ExecContext(ctx, mysqlddl)
+	myConf, err := mysqlConfig()
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	return db, nil
+	fmt.Println(pgConf)
+	fmt.Println(myConf)
+	return nil
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("no name")
-	}
-	exname := os.Args[1]
-	pgDriver := ""
-	if 3 <= len(os.Args) {
-		pgDriver = os.Args[2]
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	db, err := setup(ctx, exname, pgDriver)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			slog.WarnContext(ctx, "Close", "err", err)
+	flag.Parse()
+	if *printConfigFlag {
+		if err := printConfig(); err != nil {
+			log.Fatal(err)
 		}
-	}()
-
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
-
-	switch {
-	case strings.EqualFold(exname, "Ex01MySQL01"):
-		err = Ex01MySQL01(ctx, db)
-	case strings.EqualFold(exname, "Ex01MySQL02"):
-		err = Ex01MySQL02(ctx, db)
-	case strings.EqualFold(exname, "Ex01MySQL03"):
-		err = Ex01MySQL03(ctx, db)
-	case strings.EqualFold(exname, "Ex01Pg01"):
-		err = Ex01Pg01(ctx, db)
-	case strings.EqualFold(exname, "Ex01Pg02"):
-		err = Ex01Pg02(ctx, db)
-	case strings.EqualFold(exname, "Ex01Pg03"):
-		err = Ex01Pg03(ctx, db)
-	case strings.EqualFold(exname, "Ex01Pg04"):
-		err = Ex01Pg04(ctx, db)
-	case strings.EqualFold(exname, "Ex01Pg05"):
-		err = Ex01Pg05(ctx, db)
-	case strings.EqualFold(exname, "Ex01Pg06"):
-		err = Ex01Pg06(ctx, db)
-	default:
-		err = fmt.Errorf("unknown:%s", exname)
+		return
 	}
-	if err != nil {
+
+	setup := runner.Setup{Pg: setupPg, MySQL: setupMySQL, Timeout: *timeoutFlag}
+	ctx := dbsetup.WithStartup(context.Background(), *startupFlags)
+	if err := runner.Main(ctx, flag.Args(), setup); err != nil {
 		log.Fatal(err)
 	}
 }
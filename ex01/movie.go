@@ -0,0 +1,14 @@
+package main
+
+import "time"
+
+// Movie はmovieテーブルのレコード
+type Movie struct {
+	ID        int32     `db:"id"`
+	Title     string    `db:"title"`
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+// movieColumns はMovieを読み込むSELECTのカラムの順序
+var movieColumns = []string{"id", "title", "created_at", "updated_at"}
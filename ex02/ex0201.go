@@ -6,8 +6,19 @@ import (
 	"errors"
 	"log/slog"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0201",
+		Target:      runner.Pg,
+		Description: "*sql.ConnのClose()でプールに返却する（エラー処理あり）",
+		Run:         runner.Single(Ex0201),
+	})
+}
+
 func Ex0201(ctx context.Context, db *sql.DB) error {
 	now := time.Now()
 
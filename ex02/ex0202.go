@@ -4,8 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0202",
+		Target:      runner.Pg,
+		Description: "*sql.ConnのClose()でプールに返却される",
+		Run:         runner.Single(Ex0202),
+	})
+}
+
 func Ex0202(ctx context.Context, db *sql.DB) error {
 	now := time.Now()
 
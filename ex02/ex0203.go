@@ -4,8 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0203",
+		Target:      runner.Pg,
+		Description: "*sql.DBで開始したトランザクションはCommitで返却される",
+		Run:         runner.Single(Ex0203),
+	})
+}
+
 func Ex0203(ctx context.Context, db *sql.DB) error {
 	now := time.Now()
 
@@ -4,8 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0204",
+		Target:      runner.Pg,
+		Description: "*sql.DBで開始したトランザクションはRollbackで返却される",
+		Run:         runner.Single(Ex0204),
+	})
+}
+
 func Ex0204(ctx context.Context, db *sql.DB) error {
 	now := time.Now()
 
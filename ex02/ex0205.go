@@ -4,8 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0205",
+		Target:      runner.Pg,
+		Description: "*sql.Connで開始したトランザクションはCommitでは返却されない",
+		Run:         runner.Single(Ex0205),
+	})
+}
+
 func Ex0205(ctx context.Context, db *sql.DB) error {
 	now := time.Now()
 
@@ -15,8 +26,10 @@ func Ex0205(ctx context.Context, db *sql.DB) error {
 	tx.ExecContext(ctx, "INSERT INTO shop (name, created_at) VALUES ($1, $2)", "shop2", now)
 
 	stats(db, "before")
+	serverState(ctx)         // サーバ側はidle in transaction
 	errCommit := tx.Commit() // ここでは返却されない
 	statsErr(db, "after", errCommit)
+	serverState(ctx) // サーバ側はidleだが、クライアント側はInUseのまま
 
 	tx.Rollback() // sql.ErrTxDone。ここでも返却されない
 
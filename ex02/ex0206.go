@@ -4,8 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0206",
+		Target:      runner.Pg,
+		Description: "DB.ExecContextは実行毎に返却される",
+		Run:         runner.Single(Ex0206),
+	})
+}
+
 func Ex0206(ctx context.Context, db *sql.DB) error {
 	now := time.Now()
 
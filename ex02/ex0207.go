@@ -4,8 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0207",
+		Target:      runner.Pg,
+		Description: "DB.QueryRowContextはrow.Scan()で返却される",
+		Run:         runner.Single(Ex0207),
+	})
+}
+
 func Ex0207(ctx context.Context, db *sql.DB) error {
 	db.ExecContext(ctx, "INSERT INTO shop (name, created_at) VALUES ($1, $2)", "shop1", time.Now())
 
@@ -4,8 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0208",
+		Target:      runner.Pg,
+		Description: "DB.QueryContextはrows.Next()がfalseになったときに返却される",
+		Run:         runner.Single(Ex0208),
+	})
+}
+
 func Ex0208(ctx context.Context, db *sql.DB) error {
 	now := time.Now()
 	db.ExecContext(ctx, "INSERT INTO shop (name, created_at) VALUES ($1, $2)", "shop1", now)
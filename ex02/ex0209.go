@@ -4,8 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0209",
+		Target:      runner.Pg,
+		Description: "rows.Next()がfalseになる前はrows.Close()で返却される",
+		Run:         runner.Single(Ex0209),
+	})
+}
+
 func Ex0209(ctx context.Context, db *sql.DB) error {
 	now := time.Now()
 	db.ExecContext(ctx, "INSERT INTO shop (name, created_at) VALUES ($1, $2)", "shop1", now)
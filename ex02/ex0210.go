@@ -3,8 +3,19 @@ package main
 import (
 	"context"
 	"database/sql"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0210",
+		Target:      runner.Pg,
+		Description: "DB.Closeでプールのコネクションがクローズされる",
+		Run:         runner.Single(Ex0210),
+	})
+}
+
 func Ex0210(ctx context.Context, db *sql.DB) error {
 	var conn [5]*sql.Conn
 	db.SetMaxOpenConns(len(conn))
@@ -3,8 +3,19 @@ package main
 import (
 	"context"
 	"database/sql"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0211",
+		Target:      runner.Pg,
+		Description: "DB.CloseでInUseのコネクションはクローズされずに残る",
+		Run:         runner.Single(Ex0211),
+	})
+}
+
 func Ex0211(ctx context.Context, db *sql.DB) error {
 	var conn [5]*sql.Conn
 	db.SetMaxOpenConns(len(conn))
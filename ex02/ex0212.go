@@ -0,0 +1,38 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0212",
+		Target:      runner.Pg,
+		Description: "rows.Close()を忘れるとプールに返却されない（-leakで検出する）",
+		Run:         runner.Single(Ex0212),
+	})
+}
+
+func Ex0212(ctx context.Context, db *sql.DB) error {
+	now := time.Now()
+	db.ExecContext(ctx, "INSERT INTO shop (name, created_at) VALUES ($1, $2)", "shop1", now)
+	db.ExecContext(ctx, "INSERT INTO shop (name, created_at) VALUES ($1, $2)", "shop2", now)
+
+	// 2レコード取得
+	rows, _ := db.QueryContext(ctx, "SELECT id, name FROM shop ORDER BY id LIMIT 2")
+
+	var id int32
+	var name string
+
+	// 1レコード目だけ読み込み、rows.Close()を忘れる
+	rows.Next()
+	rows.Scan(&id, &name)
+
+	statsIdName(db, "after", id, name) // 返却されずInUseのまま
+
+	return nil
+}
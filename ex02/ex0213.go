@@ -0,0 +1,45 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/loadgen"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0213",
+		Target:      runner.Pg,
+		Description: "goroutineよりコネクションが少ないと取得待ちになり、タイムアウトで失敗する",
+		Run: runner.Single(func(ctx context.Context, db *sql.DB) error {
+			return Ex0213(ctx, db, loadConfig(), loadMaxOpen())
+		}),
+	})
+}
+
+// Ex0213 はcの設定で同じクエリを同時に実行する。c.Queryはサンプル内で設定する
+func Ex0213(ctx context.Context, db *sql.DB, c loadgen.Config, maxOpen int) error {
+	now := time.Now()
+	db.ExecContext(ctx, "INSERT INTO shop (name, created_at) VALUES ($1, $2)", "shop1", now)
+	db.ExecContext(ctx, "INSERT INTO shop (name, created_at) VALUES ($1, $2)", "shop2", now)
+
+	db.SetMaxOpenConns(maxOpen)
+	stats(db, "before")
+
+	// 2レコード取得し、1レコード目を読み込んだ後にRowsを開いたまま待つ（Ex0208と同じく返却されない）
+	c.Query = "SELECT id, name FROM shop ORDER BY id LIMIT 2"
+	r := loadgen.Run(ctx, db, c)
+	slog.Info("result",
+		"workers", c.Workers, "maxOpen", maxOpen,
+		"requests", r.Requests, "succeeded", r.Succeeded, "deadline", r.Deadline, "failed", r.Failed,
+		"WaitCount", r.WaitCount, "WaitDuration", r.WaitDuration,
+		"p50", r.Percentile(50), "p99", r.Percentile(99),
+	)
+	stats(db, "after")
+
+	return nil
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0214",
+		Target:      runner.Pg,
+		Description: "ConnMaxLifetimeを過ぎたコネクションは取り出す時点でクローズされる",
+		Run:         runner.Single(Ex0214),
+	})
+}
+
+func Ex0214(ctx context.Context, db *sql.DB) error {
+	db.SetConnMaxLifetime(100 * time.Millisecond)
+
+	var pid int32
+	err := db.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid)
+	statsClosed(db, "before", pid, err)
+
+	time.Sleep(200 * time.Millisecond) // ConnMaxLifetimeを過ぎる
+
+	err = db.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid) // 新しいコネクション（pidが変わる）
+	statsClosed(db, "after", pid, err)
+
+	return nil
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0215",
+		Target:      runner.Pg,
+		Description: "ConnMaxIdleTimeを過ぎたIdleのコネクションはバックグラウンドでクローズされる",
+		Run:         runner.Single(Ex0215),
+	})
+}
+
+func Ex0215(ctx context.Context, db *sql.DB) error {
+	db.SetConnMaxIdleTime(100 * time.Millisecond)
+
+	var pid int32
+	err := db.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid)
+	statsClosed(db, "before", pid, err)
+
+	// クローズするgoroutineは最短でも1秒間隔でしか動かない
+	time.Sleep(1200 * time.Millisecond)
+	statsClosed(db, "idle", pid, nil) // 取り出す前にクローズされている
+
+	err = db.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid) // 新しいコネクション（pidが変わる）
+	statsClosed(db, "after", pid, err)
+
+	return nil
+}
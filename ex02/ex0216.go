@@ -0,0 +1,43 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0216",
+		Target:      runner.Pg,
+		Description: "サーバ側のidle_session_timeoutで切断されたコネクションの検出",
+		Run:         runner.Single(Ex0216),
+	})
+}
+
+func Ex0216(ctx context.Context, db *sql.DB) error {
+	db.SetMaxOpenConns(1)
+
+	var pid int32
+	err := db.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid)
+	statsClosed(db, "before", pid, err)
+
+	// このセッションだけ、サーバ側で500msアイドルが続くと切断されるようにする
+	db.ExecContext(ctx, "SET idle_session_timeout = 500")
+
+	time.Sleep(1500 * time.Millisecond) // サーバ側で切断される。クライアント側のプールはIdleのまま
+	stats(db, "idle")
+
+	// 1回目：切断を検出できれば別のコネクションで実行し、できなければエラーになる
+	pid = 0
+	err = db.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid)
+	statsClosed(db, "first", pid, err)
+
+	// 2回目：切断されたコネクションは破棄されている
+	err = db.QueryRowContext(ctx, "SELECT pg_backend_pid()").Scan(&pid)
+	statsClosed(db, "second", pid, err)
+
+	return nil
+}
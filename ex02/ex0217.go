@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex0217",
+		Target:      runner.Pg,
+		Description: "キャンセル（Ctrl+Cかタイムアウト）の後、処理中のコネクションの返却を待ってからクローズする",
+		Run:         runner.Single(Ex0217),
+	})
+}
+
+// handleTime はworkerの1回の処理にかかる時間
+var handleTime = 200 * time.Millisecond
+
+// runTime はシグナルでキャンセルされない場合にworkerを動かす時間
+var runTime = time.Second
+
+func Ex0217(ctx context.Context, db *sql.DB) error {
+	// run-allなどで-timeoutまで待たないように、シグナルがなくてもrunTimeで終了する
+	ctx, cancel := context.WithTimeout(ctx, runTime)
+	defer cancel()
+
+	db.ExecContext(ctx, "INSERT INTO shop (name, created_at) VALUES ($1, $2)", "shop1", time.Now())
+
+	forgetCommit(ctx, db)
+	for range 2 {
+		go worker(ctx, db)
+	}
+
+	<-ctx.Done() // キャンセルされるかrunTimeが経つまで処理を続ける
+	stats(db, "cancel")
+
+	// workerの終了は待たずに戻る。返却を待つのはrunner.SetupのClose（closePg）
+	return nil
+}
+
+// worker はキャンセルされるまで処理を繰り返す。処理中のものはキャンセルされても最後まで実行する
+func worker(ctx context.Context, db *sql.DB) {
+	for ctx.Err() == nil {
+		if err := handle(context.WithoutCancel(ctx), db); err != nil {
+			slog.Info("handle", "err", err)
+			return
+		}
+	}
+}
+
+func handle(ctx context.Context, db *sql.DB) error {
+	rows, err := db.QueryContext(ctx, "SELECT id, name FROM shop ORDER BY id LIMIT 2")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	time.Sleep(handleTime) // Rowsを開いたまま処理する
+	return nil
+}
+
+// forgetCommit はキャンセルされないコンテキストでトランザクションを開始し、Commitを忘れる
+func forgetCommit(ctx context.Context, db *sql.DB) {
+	tx, err := db.BeginTx(context.WithoutCancel(ctx), nil)
+	if err != nil {
+		return
+	}
+	tx.ExecContext(ctx, "INSERT INTO shop (name, created_at) VALUES ($1, $2)", "shop2", time.Now())
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/fakedb"
+	"github.com/ystkg/db-examples/internal/golden"
+	"github.com/ystkg/db-examples/internal/leak"
+	"github.com/ystkg/db-examples/internal/loadgen"
+	"github.com/ystkg/db-examples/internal/runner"
+	"github.com/ystkg/db-examples/internal/session"
+	"github.com/ystkg/db-examples/internal/shutdown"
+	"github.com/ystkg/db-examples/internal/sqltrace"
+	"github.com/ystkg/db-examples/internal/timeline"
+)
+
+const insertShop = "Exec INSERT INTO shop (name, created_at) VALUES ($1, $2)"
+
+var shopColumns = []string{"id", "name"}
+
+func checkTrace(t *testing.T, s *fakedb.Script, want []string) {
+	t.Helper()
+	if got := s.Trace(); !slices.Equal(got, want) {
+		t.Errorf("trace\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func countOp(s *fakedb.Script, op fakedb.Op) int {
+	n := 0
+	for _, c := range s.Calls() {
+		if c.Op == op {
+			n++
+		}
+	}
+	return n
+}
+
+func run(t *testing.T, s *fakedb.Script, f func(context.Context, *sql.DB) error) *sql.DB {
+	t.Helper()
+	db := sql.OpenDB(sqltrace.Wrap(fakedb.NewConnector(s), poolEvent))
+	t.Cleanup(func() { db.Close() })
+	out := golden.Capture(t)
+	if err := f(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	out.Assert(t)
+	return db
+}
+
+func TestEx0201(t *testing.T) {
+	s := fakedb.NewScript()
+	db := run(t, s, Ex0201)
+
+	checkTrace(t, s, []string{"Begin", insertShop, insertShop, "Commit"})
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("InUse = %d", inUse)
+	}
+}
+
+func TestEx0202(t *testing.T) {
+	s := fakedb.NewScript()
+	db := run(t, s, Ex0202)
+
+	checkTrace(t, s, []string{"Begin", insertShop, insertShop, "Commit"})
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("InUse = %d", inUse)
+	}
+}
+
+func TestEx0203(t *testing.T) {
+	s := fakedb.NewScript()
+	db := run(t, s, Ex0203)
+
+	checkTrace(t, s, []string{"Begin", insertShop, insertShop, "Commit"})
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("InUse = %d", inUse)
+	}
+}
+
+func TestEx0204(t *testing.T) {
+	s := fakedb.NewScript()
+	db := run(t, s, Ex0204)
+
+	checkTrace(t, s, []string{"Begin", insertShop, insertShop, "Rollback"})
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("InUse = %d", inUse)
+	}
+}
+
+func TestEx0205(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("SELECT pg_backend_pid()").Rows([]string{"pg_backend_pid"}, []driver.Value{int64(101)})
+	monitor := fakedb.NewScript()
+	activity := []string{"pid", "state", "in_tx", "query"}
+	monitor.Query("FROM pg_stat_activity").Rows(activity,
+		[]driver.Value{int64(101), "idle in transaction", true, "INSERT INTO shop (name, created_at) VALUES ($1, $2)"},
+	).Once()
+	monitor.Query("FROM pg_stat_activity").Rows(activity,
+		[]driver.Value{int64(101), "idle", false, "COMMIT"},
+	)
+	sessions = session.New(dialect.Postgres, fakedb.Open(monitor))
+	t.Cleanup(func() { sessions = nil })
+
+	db := sql.OpenDB(sqltrace.Wrap(sessions.Wrap(fakedb.NewConnector(s)), poolEvent))
+	t.Cleanup(func() { db.Close() })
+	out := golden.Capture(t)
+	if err := Ex0205(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	out.Assert(t)
+
+	checkTrace(t, s, []string{"Query SELECT pg_backend_pid()", "Begin", insertShop, insertShop, "Commit"})
+	if got := monitor.Calls()[1].Args; !slices.Equal(got, []any{int64(101)}) {
+		t.Errorf("args = %v", got)
+	}
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("InUse = %d", inUse)
+	}
+}
+
+func TestEx0206(t *testing.T) {
+	s := fakedb.NewScript()
+	db := run(t, s, Ex0206)
+
+	checkTrace(t, s, []string{insertShop, insertShop})
+	if open := db.Stats().OpenConnections; open != 1 {
+		t.Errorf("OpenConnections = %d", open)
+	}
+}
+
+func TestEx0207(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("SELECT id, name FROM shop").Rows(shopColumns, []driver.Value{int64(1), "shop1"})
+	db := run(t, s, Ex0207)
+
+	checkTrace(t, s, []string{insertShop, "Query SELECT id, name FROM shop ORDER BY id LIMIT 1"})
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("InUse = %d", inUse)
+	}
+}
+
+func TestEx0208(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("SELECT id, name FROM shop").Rows(shopColumns,
+		[]driver.Value{int64(1), "shop1"}, []driver.Value{int64(2), "shop2"},
+	)
+	db := run(t, s, Ex0208)
+
+	checkTrace(t, s, []string{insertShop, insertShop, "Query SELECT id, name FROM shop ORDER BY id LIMIT 2"})
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("InUse = %d", inUse)
+	}
+}
+
+func TestEx0209(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("SELECT id, name FROM shop").Rows(shopColumns,
+		[]driver.Value{int64(1), "shop1"}, []driver.Value{int64(2), "shop2"},
+	)
+	db := run(t, s, Ex0209)
+
+	checkTrace(t, s, []string{insertShop, insertShop, "Query SELECT id, name FROM shop ORDER BY id LIMIT 2"})
+	if inUse := db.Stats().InUse; inUse != 0 {
+		t.Errorf("InUse = %d", inUse)
+	}
+}
+
+func TestEx0210(t *testing.T) {
+	s := fakedb.NewScript()
+	run(t, s, Ex0210)
+
+	checkTrace(t, s, nil)
+	if n := countOp(s, fakedb.OpConnect); n != 5 {
+		t.Errorf("Connect = %d", n)
+	}
+	if n := countOp(s, fakedb.OpClose); n != 5 {
+		t.Errorf("Close = %d", n)
+	}
+}
+
+func TestEx0211(t *testing.T) {
+	s := fakedb.NewScript()
+	db := run(t, s, Ex0211)
+
+	checkTrace(t, s, nil)
+	if n := countOp(s, fakedb.OpConnect); n != 5 {
+		t.Errorf("Connect = %d", n)
+	}
+	if n := countOp(s, fakedb.OpClose); n != 5 {
+		t.Errorf("Close = %d", n)
+	}
+	if open := db.Stats().OpenConnections; open != 0 {
+		t.Errorf("OpenConnections = %d", open)
+	}
+}
+
+func TestEx0212(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("SELECT id, name FROM shop").Rows(shopColumns,
+		[]driver.Value{int64(1), "shop1"}, []driver.Value{int64(2), "shop2"},
+	)
+	detector := leak.New(0)
+	db := sql.OpenDB(detector.Wrap(sqltrace.Wrap(fakedb.NewConnector(s), poolEvent)))
+	out := golden.Capture(t)
+	if err := Ex0212(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	db.Close() // クローズされていないRowsとConnを報告する
+	out.Assert(t)
+
+	leaks := detector.Leaks()
+	if len(leaks) != 2 || leaks[0].Kind != leak.Conn || leaks[1].Kind != leak.Rows {
+		t.Fatalf("leaks = %+v", leaks)
+	}
+	if at := leaks[1].At(); !strings.HasPrefix(at, "ex02.Ex0212 (ex0212.go:") {
+		t.Errorf("at = %s", at)
+	}
+	if n := countOp(s, fakedb.OpClose); n != 0 {
+		t.Errorf("Close = %d", n)
+	}
+}
+
+func TestTimeline(t *testing.T) {
+	rec := timeline.New(0)
+	db := sql.OpenDB(rec.Wrap(fakedb.NewConnector(fakedb.NewScript())))
+	rec.Start(db)
+	timelines = []*timeline.Recorder{rec}
+	t.Cleanup(func() { timelines = nil })
+
+	golden.Capture(t)
+	if err := Ex0203(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	var b strings.Builder
+	path := filepath.Join(t.TempDir(), "timeline.json")
+	if err := writeTimelines(&b, path); err != nil {
+		t.Fatal(err)
+	}
+	var events []string
+	for _, line := range strings.Split(strings.TrimSpace(b.String()), "\n")[2:] {
+		events = append(events, strings.Fields(line)[1])
+	}
+	want := []string{"init", "connect", "begin", "exec", "exec", "commit", "checkin", "close", "final"}
+	if !slices.Equal(events, want) {
+		t.Errorf("events\n got: %q\nwant: %q", events, want)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var samples []map[string]any
+	if err := json.Unmarshal(data, &samples); err != nil {
+		t.Fatal(err)
+	}
+	if len(samples) != len(want) {
+		t.Errorf("samples = %d", len(samples))
+	}
+}
+
+func TestEx0213(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("SELECT id, name FROM shop").Rows(shopColumns,
+		[]driver.Value{int64(1), "shop1"}, []driver.Value{int64(2), "shop2"},
+	)
+	// goroutineのイベントは順序が一定にならないので出力しない
+	db := sql.OpenDB(fakedb.NewConnector(s))
+	t.Cleanup(func() { db.Close() })
+	out := golden.Capture(t)
+	c := loadgen.Config{Workers: 3, Requests: 1, Hold: 50 * time.Millisecond, Timeout: 125 * time.Millisecond}
+	if err := Ex0213(context.Background(), db, c, 1); err != nil {
+		t.Fatal(err)
+	}
+	// 3つ目は取得した時点で100ms経過しているので、Holdの途中でタイムアウトになる
+	out.Assert(t)
+}
+
+func pidScript(errs ...error) *fakedb.Script {
+	s := fakedb.NewScript()
+	s.Query("SELECT pg_backend_pid()").Rows([]string{"pg_backend_pid"}, []driver.Value{int64(101)}).Once()
+	for _, err := range errs {
+		s.Query("SELECT pg_backend_pid()").Err(err).Once()
+	}
+	s.Query("SELECT pg_backend_pid()").Rows([]string{"pg_backend_pid"}, []driver.Value{int64(102)})
+	return s
+}
+
+func TestEx0214(t *testing.T) {
+	s := pidScript()
+	db := run(t, s, Ex0214)
+
+	if n := countOp(s, fakedb.OpConnect); n != 2 {
+		t.Errorf("Connect = %d", n)
+	}
+	if closed := db.Stats().MaxLifetimeClosed; closed != 1 {
+		t.Errorf("MaxLifetimeClosed = %d", closed)
+	}
+}
+
+func TestEx0215(t *testing.T) {
+	s := pidScript()
+	db := run(t, s, Ex0215)
+
+	if n := countOp(s, fakedb.OpConnect); n != 2 {
+		t.Errorf("Connect = %d", n)
+	}
+	if closed := db.Stats().MaxIdleTimeClosed; closed != 1 {
+		t.Errorf("MaxIdleTimeClosed = %d", closed)
+	}
+}
+
+func TestEx0216(t *testing.T) {
+	// 切断されたコネクションをドライバがErrBadConnで通知する
+	s := pidScript(driver.ErrBadConn)
+	run(t, s, Ex0216)
+
+	checkTrace(t, s, []string{
+		"Query SELECT pg_backend_pid()",
+		"Exec SET idle_session_timeout = 500",
+		"Query SELECT pg_backend_pid()", // ErrBadConn
+		"Query SELECT pg_backend_pid()", // 新しいコネクションで再実行
+		"Query SELECT pg_backend_pid()",
+	})
+	if n := countOp(s, fakedb.OpClose); n != 1 {
+		t.Errorf("Close = %d", n)
+	}
+}
+
+func TestEx0217(t *testing.T) {
+	prev := *drainFlag
+	*drainFlag = 500 * time.Millisecond
+	t.Cleanup(func() { *drainFlag = prev })
+
+	s := fakedb.NewScript()
+	s.Query("SELECT id, name FROM shop").Rows(shopColumns, []driver.Value{int64(1), "shop1"})
+	detector := leak.New(0)
+	drainer = shutdown.New()
+	drainer.Leaks = detector
+	t.Cleanup(func() { drainer = nil })
+	var report strings.Builder
+	reportOut = &report
+	t.Cleanup(func() { reportOut = os.Stderr })
+	// goroutineのイベントは順序が一定にならないので出力しない
+	db := sql.OpenDB(detector.Wrap(drainer.Wrap(fakedb.NewConnector(s))))
+	out := golden.Capture(t)
+
+	// Ctrl+Cの代わりに50msでキャンセルする
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	context.AfterFunc(ctx, drainer.Stop)
+	if err := Ex0217(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	// workerの2つは返却され、Commitを忘れた1つが残る
+	if err := closePg(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	out.Assert(t)
+
+	if n := countOp(s, fakedb.OpConnect); n != 3 {
+		t.Errorf("Connect = %d", n)
+	}
+	if !drainer.Draining() {
+		t.Error("not draining")
+	}
+	// 返却されなかったConnとTxのスタックトレース
+	if got := report.String(); strings.Count(got, ".forgetCommit\n") != 2 {
+		t.Errorf("report:\n%s", got)
+	}
+}
+
+// TestLive はgo test -live -run TestLive の場合だけ、docker-compose.ymlのデータベースで実行する
+func TestLive(t *testing.T) {
+	golden.Live(t, runner.Setup{Pg: setupPg, Close: closePg})
+}
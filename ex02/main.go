@@ -3,18 +3,62 @@ package main
 import (
 	"context"
 	"database/sql"
+	"database/sql/driver"
 	_ "embed"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"log/slog"
 	"os"
+	"os/signal"
+	"path/filepath"
 	"strings"
+	"sync/atomic"
+	"syscall"
 	"time"
 
-	_ "github.com/jackc/pgx/v5/stdlib"
-	"gopkg.in/yaml.v3"
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/leak"
+	"github.com/ystkg/db-examples/internal/loadgen"
+	"github.com/ystkg/db-examples/internal/runner"
+	"github.com/ystkg/db-examples/internal/session"
+	"github.com/ystkg/db-examples/internal/shutdown"
+	"github.com/ystkg/db-examples/internal/sqltrace"
+	"github.com/ystkg/db-examples/internal/timeline"
 )
 
+var (
+	printConfigFlag = flag.Bool("print-config", false, "接続設定を表示して終了する")
+	timeoutFlag     = flag.Duration("timeout", runner.DefaultTimeout, "サンプル1つあたりのタイムアウト")
+	pgFlags         = dbsetup.RegisterFlags(flag.CommandLine, "pg")
+	startupFlags    = dbsetup.RegisterStartupFlags(flag.CommandLine)
+	poolFlags       = dbsetup.RegisterPoolFlags(flag.CommandLine, "pool")
+	leakFlag        = flag.Bool("leak", false, "クローズされていないConn、Tx、RowsをDB.Closeの時点で報告する")
+	leakAgeFlag     = flag.Duration("leak-threshold", 0, "-leakで報告する経過時間の下限")
+	drainFlag       = flag.Duration("drain", time.Second, "終了時にInUseのコネクションの返却を待つ期限")
+	timelineFlag    = flag.Bool("timeline", false, "コネクションプールの推移をチャートで出力する")
+	intervalFlag    = flag.Duration("timeline-interval", 10*time.Millisecond, "-timelineのサンプリング間隔")
+	timelineJSON    = flag.String("timeline-json", "", "-timelineの推移をJSONで出力するファイル")
+	workersFlag     = flag.Int("workers", 10, "ex0213で同時に実行するgoroutineの数")
+	requestsFlag    = flag.Int("requests", 1, "ex0213のgoroutineあたりの実行回数")
+	holdFlag        = flag.Duration("hold", 20*time.Millisecond, "ex0213でRowsを開いたまま待つ時間")
+	deadlineFlag    = flag.Duration("deadline", 50*time.Millisecond, "ex0213の1回あたりのタイムアウト（0は無制限）")
+)
+
+// timelines は-timelineで記録したプールの推移。サンプル毎に1つ
+var timelines []*timeline.Recorder
+
+// sessions はサーバ側のセッションの状態の確認用。setupPgで設定する
+var sessions *session.Observer
+
+// drainer は終了処理の状態。setupPgで設定し、closePgで使う
+var drainer *shutdown.Drainer
+
+// reportOut はclosePgで返却されなかったもののスタックトレースを出力する先
+var reportOut io.Writer = os.Stderr
+
 var (
 	//go:embed docker-compose.yml
 	yml []byte
@@ -26,47 +70,136 @@ var (
 	pgclean string
 )
 
-func setup(ctx context.Context) (*sql.DB, error) {
-	return setupPg(ctx)
-}
-
-func setupPg(ctx context.Context) (*sql.DB, error) {
-	conf := struct {
-		Services struct {
-			Postgres struct {
-				Ports       []string
-				Environment struct {
-					PostgresPassword string `yaml:"POSTGRES_PASSWORD"`
-				}
-			}
-		}
-	}{}
-	if err := yaml.Unmarshal(yml, &conf); err != nil {
-		return nil, err
+func pgConfig(driverName string) (dbsetup.Config, error) {
+	conf, err := dbsetup.LoadPostgres(yml)
+	if err != nil {
+		return dbsetup.Config{}, err
 	}
+	conf.Driver = driverName
+	conf.TimeZone = "Asia/Tokyo"
 
-	db, err := sql.Open("pgx",
-		fmt.Sprintf("postgres://postgres:%s@localhost:5432/postgres?sslmode=disable&TimeZone=Asia/Tokyo",
-			conf.Services.Postgres.Environment.PostgresPassword,
-		),
-	)
+	return dbsetup.Resolve(conf, pgFlags)
+}
+
+func setupPg(ctx context.Context, driverName string) (*sql.DB, error) {
+	conf, err := pgConfig(driverName)
 	if err != nil {
 		return nil, err
 	}
 
-	_, err = db.ExecContext(ctx, pgclean)
+	// キャンセル（シグナルかタイムアウト）の後は新しい接続を拒否する
+	drainer = shutdown.New()
+	context.AfterFunc(ctx, drainer.Stop)
+	ctx = dbsetup.WithWrapper(ctx, drainer.Wrap)
+
+	// サーバ側のセッションの状態は、観測対象のプールとは別の*sql.DBで確認する
+	monitor, err := dbsetup.Open(context.Background(), conf)
 	if err != nil {
 		return nil, err
 	}
+	sessions = session.New(dialect.Postgres, monitor)
+	ctx = dbsetup.WithWrapper(ctx, sessions.Wrap)
 
-	_, err = db.ExecContext(ctx, pgddl)
+	// テーブルの初期化が終わってからコネクションのイベントを出力する
+	tracing := &atomic.Bool{}
+	ctx = dbsetup.WithWrapper(ctx, func(c driver.Connector) driver.Connector {
+		return sqltrace.Wrap(c, func(ev sqltrace.Event) {
+			if tracing.Load() {
+				poolEvent(ev)
+			}
+		})
+	})
+	if *leakFlag {
+		detector := leak.New(*leakAgeFlag)
+		drainer.Leaks = detector
+		ctx = dbsetup.WithWrapper(ctx, detector.Wrap)
+	}
+	var rec *timeline.Recorder
+	if *timelineFlag {
+		rec = timeline.New(*intervalFlag)
+		ctx = dbsetup.WithWrapper(ctx, rec.Wrap)
+	}
+	db, err := dbsetup.OpenReset(ctx, conf, pgclean, pgddl)
 	if err != nil {
+		monitor.Close()
 		return nil, err
 	}
+	poolFlags.Apply(db)
+	tracing.Store(true)
+	if rec != nil {
+		rec.Start(db)
+		timelines = append(timelines, rec)
+	}
 
 	return db, nil
 }
 
+// closePg は-drainの期限までInUseのコネクションの返却を待ってからクローズする
+// 返却されなかったものは、-leakを指定していれば取得した時点のスタックトレースをreportOutに出力する
+func closePg(ctx context.Context, db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, *drainFlag)
+	defer cancel()
+	r, err := drainer.Shutdown(ctx, db)
+	if len(r.Leaks) != 0 {
+		if err := leak.Write(reportOut, r.Leaks); err != nil {
+			return err
+		}
+	}
+	return err
+}
+
+// ex0213DefaultMaxOpen は-pool-max-openを指定しない場合のex0213のSetMaxOpenConns
+const ex0213DefaultMaxOpen = 2
+
+// loadConfig はex0213の負荷の設定をフラグから組み立てる
+func loadConfig() loadgen.Config {
+	return loadgen.Config{
+		Workers:  *workersFlag,
+		Requests: *requestsFlag,
+		Hold:     *holdFlag,
+		Timeout:  *deadlineFlag,
+	}
+}
+
+// loadMaxOpen はex0213のSetMaxOpenConns。取り合いになるように、指定しない場合はworkersより少なくする
+func loadMaxOpen() int {
+	if poolFlags.MaxOpen != 0 {
+		return poolFlags.MaxOpen
+	}
+	return ex0213DefaultMaxOpen
+}
+
+// poolEvent はコネクションのイベントを物理コネクションの番号（conn）と一緒に出力する
+// checkinがプールへの返却、reset-sessionが使用済みのコネクションの取り出しに当たる
+func poolEvent(ev sqltrace.Event) {
+	if ev.Op == sqltrace.OpStart {
+		return
+	}
+	args := []any{"conn", ev.ConnID}
+	switch ev.Op {
+	case sqltrace.OpExec, sqltrace.OpQuery:
+		args = append(args, "query", ev.Query)
+	case sqltrace.OpRowsClose:
+		args = append(args, "rows", ev.Rows)
+	case sqltrace.OpCheckin:
+		args = append(args, "valid", ev.Valid)
+	}
+	if ev.Err != nil {
+		args = append(args, "err", ev.Err)
+	}
+	slog.Info(fmt.Sprintf("%-6s", ev.Op), args...)
+}
+
+func printConfig() error {
+	conf, err := pgConfig(dbsetup.DriverPgx)
+	if err != nil {
+		return err
+	}
+	fmt.Println(conf)
+	fmt.Println(*poolFlags)
+	return nil
+}
+
 func stats(db *sql.DB, msg string) {
 	stats := db.Stats()
 	slog.Info(fmt.Sprintf("%-6s", msg), "Open", stats.OpenConnections, "InUse", stats.InUse, "Idle", stats.Idle)
@@ -77,59 +210,95 @@ func statsIdName(db *sql.DB, msg string, id int32, name string) {
 	slog.Info(fmt.Sprintf("%-6s", msg), "Open", stats.OpenConnections, "InUse", stats.InUse, "Idle", stats.Idle, "id", id, "name", name)
 }
 
+// serverState はサーバ側のセッションの状態を物理コネクション毎に出力する
+func serverState(ctx context.Context) {
+	if sessions == nil {
+		return
+	}
+	list, err := sessions.Sessions(ctx)
+	if err != nil {
+		slog.Warn("server", "err", err)
+		return
+	}
+	for _, s := range list {
+		slog.Info("server", "conn", s.ConnID, "pid", s.ID, "state", s.State, "query", s.Query)
+	}
+}
+
+// statsClosed はConnMaxLifetimeとConnMaxIdleTimeによってクローズされた数も出力する
+func statsClosed(db *sql.DB, msg string, pid int32, err error) {
+	stats := db.Stats()
+	args := []any{"Open", stats.OpenConnections, "InUse", stats.InUse, "Idle", stats.Idle,
+		"MaxLifetimeClosed", stats.MaxLifetimeClosed, "MaxIdleTimeClosed", stats.MaxIdleTimeClosed, "pid", pid,
+	}
+	if err != nil {
+		args = append(args, "err", err)
+	}
+	slog.Info(fmt.Sprintf("%-6s", msg), args...)
+}
+
 func statsErr(db *sql.DB, msg string, err error) {
 	stats := db.Stats()
 	slog.Info(fmt.Sprintf("%-6s", msg), "Open", stats.OpenConnections, "InUse", stats.InUse, "Idle", stats.Idle, "err", err)
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("no name")
+	flag.Parse()
+	if *printConfigFlag {
+		if err := printConfig(); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-	exname := os.Args[1]
 
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
+	// SIGINTかSIGTERMでサンプルのコンテキストをキャンセルし、closePgで返却を待ってからクローズする
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ctx = dbsetup.WithStartup(ctx, *startupFlags)
 
-	db, err := setup(ctx)
+	setup := runner.Setup{Pg: setupPg, Timeout: *timeoutFlag, Close: closePg}
+	err := runner.Main(ctx, flag.Args(), setup)
+	if err := writeTimelines(os.Stdout, *timelineJSON); err != nil {
+		log.Fatal(err)
+	}
 	if err != nil {
 		log.Fatal(err)
 	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			slog.WarnContext(ctx, "Close", "err", err)
+}
+
+// writeTimelines は記録したプールの推移をwにチャートで出力する
+// jsonPathを指定した場合はJSONでも出力する。複数ある場合はファイル名に連番を付ける（timeline-1.jsonなど）
+func writeTimelines(w io.Writer, jsonPath string) error {
+	for i, rec := range timelines {
+		rec.Stop()
+		samples := rec.Samples()
+		fmt.Fprintf(w, "=== timeline %d\n", i+1)
+		if err := timeline.WriteChart(w, samples); err != nil {
+			return err
+		}
+		if jsonPath == "" {
+			continue
+		}
+		path := jsonPath
+		if 1 < len(timelines) {
+			ext := filepath.Ext(jsonPath)
+			path = fmt.Sprintf("%s-%d%s", strings.TrimSuffix(jsonPath, ext), i+1, ext)
+		}
+		if err := writeJSONFile(path, samples); err != nil {
+			return err
 		}
-	}()
-
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
-
-	switch {
-	case strings.EqualFold(exname, "Ex0201"):
-		err = Ex0201(ctx, db)
-	case strings.EqualFold(exname, "Ex0202"):
-		err = Ex0202(ctx, db)
-	case strings.EqualFold(exname, "Ex0203"):
-		err = Ex0203(ctx, db)
-	case strings.EqualFold(exname, "Ex0204"):
-		err = Ex0204(ctx, db)
-	case strings.EqualFold(exname, "Ex0205"):
-		err = Ex0205(ctx, db)
-	case strings.EqualFold(exname, "Ex0206"):
-		err = Ex0206(ctx, db)
-	case strings.EqualFold(exname, "Ex0207"):
-		err = Ex0207(ctx, db)
-	case strings.EqualFold(exname, "Ex0208"):
-		err = Ex0208(ctx, db)
-	case strings.EqualFold(exname, "Ex0209"):
-		err = Ex0209(ctx, db)
-	case strings.EqualFold(exname, "Ex0210"):
-		err = Ex0210(ctx, db)
-	case strings.EqualFold(exname, "Ex0211"):
-		err = Ex0211(ctx, db)
-	default:
-		err = fmt.Errorf("unknown:%s", exname)
 	}
+	return nil
+}
+
+func writeJSONFile(path string, samples []timeline.Sample) error {
+	f, err := os.Create(path)
 	if err != nil {
-		log.Fatal(err)
+		return err
+	}
+	if err := timeline.WriteJSON(f, samples); err != nil {
+		f.Close()
+		return err
 	}
+	return f.Close()
 }
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"slices"
+	"testing"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+var staffColumns = []string{"id", "name", "role"}
+
+func staffScript() *fakedb.Script {
+	s := fakedb.NewScript()
+	s.Query("FROM staff").Rows(staffColumns, []driver.Value{int64(2), "Bob", "audience"})
+	return s
+}
+
+func checkTrace(t *testing.T, s *fakedb.Script, want []string) {
+	t.Helper()
+	if got := s.Trace(); !slices.Equal(got, want) {
+		t.Errorf("trace\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func checkArgs(t *testing.T, s *fakedb.Script, want ...any) {
+	t.Helper()
+	var got []any
+	for _, c := range s.Calls() {
+		if c.Op == fakedb.OpQuery {
+			got = append(got, c.Args...)
+		}
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("args = %q, want %q", got, want)
+	}
+}
+
+func run(t *testing.T, s *fakedb.Script, f func(context.Context, *sql.DB) error) {
+	t.Helper()
+	db := fakedb.Open(s)
+	t.Cleanup(func() { db.Close() })
+	if err := f(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestEx03Pg01(t *testing.T) {
+	s := staffScript()
+	run(t, s, Ex03Pg01)
+
+	const query = "SELECT id, name, role FROM staff WHERE name = $1"
+	checkTrace(t, s, []string{"Prepare " + query, "Query " + query, "Query " + query})
+	checkArgs(t, s, "Bob", "Carol")
+}
+
+func TestEx03Pg02(t *testing.T) {
+	s := staffScript()
+	run(t, s, Ex03Pg02)
+
+	const query = "SELECT id, name, role FROM staff WHERE name = $1"
+	checkTrace(t, s, []string{"Query " + query, "Query " + query})
+	checkArgs(t, s, "Bob", "Carol")
+}
+
+func TestEx03Pg06(t *testing.T) {
+	s := staffScript()
+	run(t, s, Ex03Pg06)
+
+	checkTrace(t, s, []string{"Query SELECT id, name, role FROM staff WHERE name = $1"})
+	checkArgs(t, s, "Bob' OR '1' = '1")
+}
+
+func TestEx03MySQL01(t *testing.T) {
+	s := staffScript()
+	run(t, s, Ex03MySQL01)
+
+	const query = "SELECT id, name, role FROM staff WHERE name = ?"
+	checkTrace(t, s, []string{"Prepare " + query, "Query " + query, "Query " + query})
+	checkArgs(t, s, "Bob", "Carol")
+}
+
+func TestEx03MySQL02(t *testing.T) {
+	s := staffScript()
+	run(t, s, Ex03MySQL02)
+
+	const query = "SELECT id, name, role FROM staff WHERE name = ?"
+	checkTrace(t, s, []string{"Query " + query, "Query " + query})
+	checkArgs(t, s, "Bob", "Carol")
+}
+
+func TestEx03MySQL05(t *testing.T) {
+	s := staffScript()
+	run(t, s, Ex03MySQL05)
+
+	checkTrace(t, s, []string{"Query SELECT id, name, role FROM staff WHERE name = ?"})
+	checkArgs(t, s, "Bob' OR '1' = '1")
+}
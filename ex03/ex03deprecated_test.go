@@ -0,0 +1,53 @@
+//go:build deprecated
+
+package main
+
+import "testing"
+
+func TestEx03Pg03(t *testing.T) {
+	s := staffScript()
+	run(t, s, Ex03Pg03)
+
+	checkTrace(t, s, []string{
+		"Query SELECT id, name, role FROM staff WHERE name = 'Bob'",
+		"Query SELECT id, name, role FROM staff WHERE name = 'Carol'",
+	})
+	checkArgs(t, s)
+}
+
+func TestEx03Pg04(t *testing.T) {
+	s := staffScript()
+	run(t, s, Ex03Pg04)
+
+	checkTrace(t, s, []string{"Query SELECT id, name, role FROM staff WHERE name = 'Bob' OR '1' = '1'"})
+	checkArgs(t, s)
+}
+
+func TestEx03Pg05(t *testing.T) {
+	s := staffScript()
+	run(t, s, Ex03Pg05)
+
+	const query = "SELECT id, name, role FROM staff WHERE name = 'Bob' OR '1' = '1'"
+	checkTrace(t, s, []string{"Prepare " + query, "Query " + query})
+	checkArgs(t, s)
+}
+
+func TestEx03MySQL03(t *testing.T) {
+	s := staffScript()
+	run(t, s, Ex03MySQL03)
+
+	checkTrace(t, s, []string{
+		"Query SELECT id, name, role FROM staff WHERE name = 'Bob'",
+		"Query SELECT id, name, role FROM staff WHERE name = 'Carol'",
+	})
+	checkArgs(t, s)
+}
+
+func TestEx03MySQL04(t *testing.T) {
+	s := staffScript()
+	run(t, s, Ex03MySQL04)
+
+	const query = "SELECT id, name, role FROM staff WHERE name = 'Bob' OR '1' = '1'"
+	checkTrace(t, s, []string{"Prepare " + query, "Query " + query})
+	checkArgs(t, s)
+}
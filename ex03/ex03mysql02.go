@@ -3,8 +3,19 @@ package main
 import (
 	"context"
 	"database/sql"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex03MySQL02",
+		Target:      runner.MySQL,
+		Description: "PrepareContextを使わずQueryContextを使ったときのクエリーログを確認する",
+		Run:         runner.Single(Ex03MySQL02),
+	})
+}
+
 func Ex03MySQL02(ctx context.Context, db *sql.DB) error {
 	conn, err := db.Conn(ctx)
 	if err != nil {
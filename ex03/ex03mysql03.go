@@ -6,8 +6,19 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex03MySQL03",
+		Target:      runner.MySQL,
+		Description: "文字列操作でSQLを組み立てたときのクエリーログを確認する",
+		Run:         runner.Single(Ex03MySQL03),
+	})
+}
+
 // Deprecated: 対比説明用
 func Ex03MySQL03(ctx context.Context, db *sql.DB) error {
 	conn, err := db.Conn(ctx)
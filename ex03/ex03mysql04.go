@@ -6,8 +6,19 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex03MySQL04",
+		Target:      runner.MySQL,
+		Description: "文字列操作で組み立てたSQLに不正なパラメータを与える",
+		Run:         runner.Single(Ex03MySQL04),
+	})
+}
+
 // Deprecated: 対比説明用
 func Ex03MySQL04(ctx context.Context, db *sql.DB) error {
 	conn, err := db.Conn(ctx)
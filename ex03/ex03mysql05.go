@@ -3,8 +3,19 @@ package main
 import (
 	"context"
 	"database/sql"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex03MySQL05",
+		Target:      runner.MySQL,
+		Description: "プレースホルダを使って不正なパラメータを与える",
+		Run:         runner.Single(Ex03MySQL05),
+	})
+}
+
 func Ex03MySQL05(ctx context.Context, db *sql.DB) error {
 	conn, err := db.Conn(ctx)
 	if err != nil {
@@ -6,10 +6,30 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
 var ErrNotImplemented = errors.New("not implemented")
 
+func init() {
+	for _, e := range []runner.Example{
+		{Name: "Ex03MySQL03", Target: runner.MySQL, Run: runner.Single(Ex03MySQL03)},
+		{Name: "Ex03MySQL04", Target: runner.MySQL, Run: runner.Single(Ex03MySQL04)},
+		{Name: "Ex03Pg03", Target: runner.Pg, Run: runner.Single(Ex03Pg03)},
+		{Name: "Ex03Pg04", Target: runner.Pg, Run: runner.Single(Ex03Pg04)},
+		{Name: "Ex03Pg05", Target: runner.Pg, Run: runner.Single(Ex03Pg05)},
+	} {
+		e.Description = "deprecatedタグの指定が必要"
+		e.ExpectErr = ErrNotImplemented
+		if e.Target == runner.Pg {
+			e.Drivers = []string{dbsetup.DriverPgx, dbsetup.DriverPq}
+		}
+		runner.Register(e)
+	}
+}
+
 func Ex03MySQL03(ctx context.Context, db *sql.DB) error {
 	return ErrNotImplemented
 }
@@ -0,0 +1,33 @@
+//go:build !deprecated
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+func TestNotImplemented(t *testing.T) {
+	for name, f := range map[string]func(context.Context, *sql.DB) error{
+		"Ex03MySQL03": Ex03MySQL03,
+		"Ex03MySQL04": Ex03MySQL04,
+		"Ex03Pg03":    Ex03Pg03,
+		"Ex03Pg04":    Ex03Pg04,
+		"Ex03Pg05":    Ex03Pg05,
+	} {
+		t.Run(name, func(t *testing.T) {
+			s := staffScript()
+			db := fakedb.Open(s)
+			defer db.Close()
+
+			if err := f(context.Background(), db); !errors.Is(err, ErrNotImplemented) {
+				t.Errorf("err = %v", err)
+			}
+			checkTrace(t, s, nil)
+		})
+	}
+}
@@ -3,8 +3,21 @@ package main
 import (
 	"context"
 	"database/sql"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex03Pg02",
+		Target:      runner.Pg,
+		Description: "PrepareContextを使わずQueryContextを使ったときのクエリーログを確認する",
+		Drivers:     []string{dbsetup.DriverPgx, dbsetup.DriverPq},
+		Run:         runner.Single(Ex03Pg02),
+	})
+}
+
 func Ex03Pg02(ctx context.Context, db *sql.DB) error {
 	conn, err := db.Conn(ctx)
 	if err != nil {
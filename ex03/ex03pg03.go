@@ -6,8 +6,21 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex03Pg03",
+		Target:      runner.Pg,
+		Description: "文字列操作でSQLを組み立てたときのクエリーログを確認する",
+		Drivers:     []string{dbsetup.DriverPgx, dbsetup.DriverPq},
+		Run:         runner.Single(Ex03Pg03),
+	})
+}
+
 // Deprecated: 対比説明用
 func Ex03Pg03(ctx context.Context, db *sql.DB) error {
 	conn, err := db.Conn(ctx)
@@ -4,17 +4,20 @@ import (
 	"context"
 	"database/sql"
 	_ "embed"
+	"flag"
 	"fmt"
 	"log"
-	"log/slog"
-	"os"
-	"strings"
-	"time"
-
-	"github.com/go-sql-driver/mysql"
-	_ "github.com/jackc/pgx/v5/stdlib"
-	_ "github.com/lib/pq"
-	"gopkg.in/yaml.v3"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+var (
+	printConfigFlag = flag.Bool("print-config", false, "接続設定を表示して終了する")
+	timeoutFlag     = flag.Duration("timeout", runner.DefaultTimeout, "サンプル1つあたりのタイムアウト")
+	pgFlags         = dbsetup.RegisterFlags(flag.CommandLine, "pg")
+	startupFlags    = dbsetup.RegisterStartupFlags(flag.CommandLine)
+	mysqlFlags      = dbsetup.RegisterFlags(flag.CommandLine, "mysql")
 )
 
 var (
@@ -40,162 +43,70 @@ var (
 	pgclean string
 )
 
-func setup(ctx context.Context, exname, pgDriver string) (*sql.DB, error) {
-	if len(exname) < 5 {
-		return nil, fmt.Errorf("unknown:%s", exname)
-	}
-	name := strings.ToUpper(exname[4:])
-	pgDriverName := "pgx"
-	if strings.EqualFold(pgDriver, "pq") {
-		pgDriverName = "postgres"
-	}
-	switch {
-	case strings.HasPrefix(name, "PG"):
-		return setupPg(ctx, pgDriverName)
-	case strings.HasPrefix(name, "MYSQL"):
-		return setupMySQL(ctx)
-	}
-	return nil, fmt.Errorf("unknown:%s", exname)
-}
-
-func setupPg(ctx context.Context, driverName string) (*sql.DB, error) {
-	conf := struct {
-		Services struct {
-			Postgres struct {
-				Ports       []string
-				Environment struct {
-					PostgresPassword string `yaml:"POSTGRES_PASSWORD"`
-				}
-			}
-		}
-	}{}
-	if err := yaml.Unmarshal(yml, &conf); err != nil {
-		return nil, err
-	}
-
-	db, err := sql.Open(driverName,
-		fmt.Sprintf("postgres://postgres:%s@localhost:5432/postgres?sslmode=disable&log_statement=all",
-			conf.Services.Postgres.Environment.PostgresPassword,
-		),
-	)
+func pgConfig(driverName string) (dbsetup.Config, error) {
+	conf, err := dbsetup.LoadPostgres(yml)
 	if err != nil {
-		return nil, err
+		return dbsetup.Config{}, err
 	}
+	conf.Driver = driverName
+	conf.Params = map[string]string{"log_statement": "all"}
 
-	_, err = db.ExecContext(ctx, pgclean)
-	if err != nil {
-		return nil, err
-	}
+	return dbsetup.Resolve(conf, pgFlags)
+}
 
-	_, err = db.ExecContext(ctx, pgddl)
+func mysqlConfig() (dbsetup.Config, error) {
+	conf, err := dbsetup.LoadMySQL(yml)
 	if err != nil {
-		return nil, err
+		return dbsetup.Config{}, err
 	}
 
-	_, err = db.ExecContext(ctx, pgdml)
+	return dbsetup.Resolve(conf, mysqlFlags)
+}
+
+func setupPg(ctx context.Context, driverName string) (*sql.DB, error) {
+	conf, err := pgConfig(driverName)
 	if err != nil {
 		return nil, err
 	}
 
-	return db, nil
+	return dbsetup.OpenReset(ctx, conf, pgclean, pgddl, pgdml)
 }
 
 func setupMySQL(ctx context.Context) (*sql.DB, error) {
-	conf := struct {
-		Services struct {
-			Mysql struct {
-				Environment struct {
-					MysqlRootPassword string `yaml:"MYSQL_ROOT_PASSWORD"`
-					MysqlDatabase     string `yaml:"MYSQL_DATABASE"`
-				}
-			}
-		}
-	}{}
-	if err := yaml.Unmarshal(yml, &conf); err != nil {
-		return nil, err
-	}
-
-	conn, err := mysql.NewConnector(&mysql.Config{
-		Addr:   "localhost:3306",
-		DBName: conf.Services.Mysql.Environment.MysqlDatabase,
-		User:   "root",
-		Passwd: conf.Services.Mysql.Environment.MysqlRootPassword,
-	})
+	conf, err := mysqlConfig()
 	if err != nil {
 		return nil, err
 	}
-	db := sql.OpenDB(conn)
 
-	_, err = db.ExecContext(ctx, mysqlclean)
-	if err != nil {
-		return nil, err
-	}
+	return dbsetup.OpenReset(ctx, conf, mysqlclean, mysqlddl, mysqldml)
+}
 
-	_, err = db.ExecContext(ctx, mysqlddl)
+func printConfig() error {
+	pgConf, err := pgConfig(dbsetup.DriverPgx)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	_, err = db.ExecContext(ctx, mysqldml)
+	myConf, err := mysqlConfig()
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	return db, nil
+	fmt.Println(pgConf)
+	fmt.Println(myConf)
+	return nil
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("no name")
-	}
-	exname := os.Args[1]
-	pgDriver := ""
-	if 3 <= len(os.Args) {
-		pgDriver = os.Args[2]
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	db, err := setup(ctx, exname, pgDriver)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer func() {
-		if err := db.Close(); err != nil {
-			slog.WarnContext(ctx, "Close", "err", err)
+	flag.Parse()
+	if *printConfigFlag {
+		if err := printConfig(); err != nil {
+			log.Fatal(err)
 		}
-	}()
-
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
-
-	switch {
-	case strings.EqualFold(exname, "Ex03Pg01"):
-		err = Ex03Pg01(ctx, db)
-	case strings.EqualFold(exname, "Ex03Pg02"):
-		err = Ex03Pg02(ctx, db)
-	case strings.EqualFold(exname, "Ex03Pg03"):
-		err = Ex03Pg03(ctx, db)
-	case strings.EqualFold(exname, "Ex03Pg04"):
-		err = Ex03Pg04(ctx, db)
-	case strings.EqualFold(exname, "Ex03Pg05"):
-		err = Ex03Pg05(ctx, db)
-	case strings.EqualFold(exname, "Ex03Pg06"):
-		err = Ex03Pg06(ctx, db)
-	case strings.EqualFold(exname, "Ex03MySQL01"):
-		err = Ex03MySQL01(ctx, db)
-	case strings.EqualFold(exname, "Ex03MySQL02"):
-		err = Ex03MySQL02(ctx, db)
-	case strings.EqualFold(exname, "Ex03MySQL03"):
-		err = Ex03MySQL03(ctx, db)
-	case strings.EqualFold(exname, "Ex03MySQL04"):
-		err = Ex03MySQL04(ctx, db)
-	case strings.EqualFold(exname, "Ex03MySQL05"):
-		err = Ex03MySQL05(ctx, db)
-	default:
-		err = fmt.Errorf("unknown:%s", exname)
+		return
 	}
-	if err != nil {
+
+	setup := runner.Setup{Pg: setupPg, MySQL: setupMySQL, Timeout: *timeoutFlag}
+	ctx := dbsetup.WithStartup(context.Background(), *startupFlags)
+	if err := runner.Main(ctx, flag.Args(), setup); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/fakedb"
+	"github.com/ystkg/db-examples/internal/golden"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+const (
+	insertShop = "Exec INSERT INTO shop (name) VALUES ($1)"
+	deleteShop = "Exec DELETE FROM shop WHERE NAME = ?"
+)
+
+func checkTrace(t *testing.T, name string, s *fakedb.Script, want []string) {
+	t.Helper()
+	if got := s.Trace(); !slices.Equal(got, want) {
+		t.Errorf("%s trace\n got: %q\nwant: %q", name, got, want)
+	}
+}
+
+func run(t *testing.T, pg, my *fakedb.Script, f func(context.Context, *sql.DB, *sql.DB) error) error {
+	t.Helper()
+	pgDB, myDB := fakedb.Open(pg), fakedb.Open(my)
+	t.Cleanup(func() {
+		pgDB.Close()
+		myDB.Close()
+	})
+	out := golden.Capture(t)
+	err := f(context.Background(), pgDB, myDB)
+	out.Assert(t)
+	return err
+}
+
+func TestEx04Tx01(t *testing.T) {
+	pg, my := fakedb.NewScript(), fakedb.NewScript()
+	if err := run(t, pg, my, Ex04Tx01); err != nil {
+		t.Fatal(err)
+	}
+
+	checkTrace(t, "pg", pg, []string{"Begin", insertShop, "Commit"})
+	checkTrace(t, "mysql", my, []string{"Begin", deleteShop, "Commit"})
+}
+
+func TestEx04Tx01CommitError(t *testing.T) {
+	pg, my := fakedb.NewScript(), fakedb.NewScript()
+	errCommit := errors.New("commit failed")
+	pg.Commit().Err(errCommit)
+	if err := run(t, pg, my, Ex04Tx01); !errors.Is(err, errCommit) {
+		t.Fatalf("err = %v", err)
+	}
+
+	// PostgreSQLのコミットに失敗するとMySQL側はロールバックされる
+	checkTrace(t, "pg", pg, []string{"Begin", insertShop, "Commit"})
+	checkTrace(t, "mysql", my, []string{"Begin", deleteShop, "Rollback"})
+}
+
+func TestEx04Tx02(t *testing.T) {
+	pg, my := fakedb.NewScript(), fakedb.NewScript()
+	if err := run(t, pg, my, Ex04Tx02); err != nil {
+		t.Fatal(err)
+	}
+
+	checkTrace(t, "pg", pg, []string{"Exec begin", insertShop, "Exec commit"})
+	checkTrace(t, "mysql", my, []string{"Exec START TRANSACTION", deleteShop, "Exec COMMIT"})
+}
+
+func TestEx04Xa01(t *testing.T) {
+	pg, my := fakedb.NewScript(), fakedb.NewScript()
+	if err := run(t, pg, my, Ex04Xa01); err != nil {
+		t.Fatal(err)
+	}
+
+	checkTrace(t, "pg", pg, []string{
+		"Exec begin",
+		insertShop,
+		"Exec prepare transaction 'shop3rd2pc'",
+		"Exec commit prepared 'shop3rd2pc'",
+	})
+	checkTrace(t, "mysql", my, []string{
+		"Exec XA BEGIN 'shop3rd2pc'",
+		deleteShop,
+		"Exec XA END 'shop3rd2pc'",
+		"Exec XA PREPARE 'shop3rd2pc'",
+		"Exec XA COMMIT 'shop3rd2pc'",
+	})
+}
+
+func TestEx04Xa02(t *testing.T) {
+	pg, my := fakedb.NewScript(), fakedb.NewScript()
+	if err := run(t, pg, my, Ex04Xa02); err != nil {
+		t.Fatal(err)
+	}
+
+	checkTrace(t, "pg", pg, []string{
+		"Exec begin",
+		insertShop,
+		"Exec prepare transaction 'shop4th2pc'",
+	})
+	checkTrace(t, "mysql", my, []string{
+		"Exec XA BEGIN 'shop4th2pc'",
+		deleteShop,
+		"Exec XA END 'shop4th2pc'",
+		"Exec XA PREPARE 'shop4th2pc'",
+	})
+}
+
+func TestEx04Xa01Rollback(t *testing.T) {
+	pg, my := fakedb.NewScript(), fakedb.NewScript()
+	errPrepare := errors.New("xa prepare failed")
+	my.Exec("XA PREPARE").Err(errPrepare)
+	pg.Exec("rollback prepared").Err(&pgconn.PgError{Code: "42704"}) // 分類してログを出力しない
+	my.Exec("XA ROLLBACK").Err(errors.New("connection reset"))       // MySQLのエラーでなくてもパニックしない
+	if err := run(t, pg, my, Ex04Xa01); !errors.Is(err, errPrepare) {
+		t.Fatalf("err = %v", err)
+	}
+
+	checkTrace(t, "pg", pg, []string{
+		"Exec begin",
+		insertShop,
+		"Exec prepare transaction 'shop3rd2pc'",
+		"Exec rollback prepared 'shop3rd2pc'",
+	})
+	checkTrace(t, "mysql", my, []string{
+		"Exec XA BEGIN 'shop3rd2pc'",
+		deleteShop,
+		"Exec XA END 'shop3rd2pc'",
+		"Exec XA PREPARE 'shop3rd2pc'",
+		"Exec XA ROLLBACK 'shop3rd2pc'",
+	})
+}
+
+func TestEx04Xa02UnknownXID(t *testing.T) {
+	pg, my := fakedb.NewScript(), fakedb.NewScript()
+	errDelete := errors.New("delete failed")
+	my.Exec("DELETE FROM shop").Err(errDelete)
+	my.Exec("XA ROLLBACK").Err(&mysql.MySQLError{Number: 1397}) // 分類してログを出力しない
+	if err := run(t, pg, my, Ex04Xa02); !errors.Is(err, errDelete) {
+		t.Fatalf("err = %v", err)
+	}
+
+	checkTrace(t, "mysql", my, []string{
+		"Exec XA BEGIN 'shop4th2pc'",
+		deleteShop,
+		"Exec XA ROLLBACK 'shop4th2pc'",
+	})
+}
+
+func TestEx04Unique01(t *testing.T) {
+	pg, my := fakedb.NewScript(), fakedb.NewScript()
+	pg.Exec("INSERT INTO shop").Result(1, 1).Once()
+	pg.Exec("INSERT INTO shop").Err(&pgconn.PgError{Code: "23505"})
+	my.Exec("INSERT INTO shop").Err(&mysql.MySQLError{Number: 1062})
+	if err := run(t, pg, my, Ex04Unique01); err != nil {
+		t.Fatal(err)
+	}
+
+	checkTrace(t, "pg", pg, []string{insertShop, insertShop})
+	checkTrace(t, "mysql", my, []string{"Exec INSERT INTO shop (name) VALUES (?)"})
+}
+
+func TestEx04Unique01OtherError(t *testing.T) {
+	pg, my := fakedb.NewScript(), fakedb.NewScript()
+	errInsert := &pgconn.PgError{Code: "23503"} // 一意制約違反以外はエラーにする
+	pg.Exec("INSERT INTO shop").Err(errInsert)
+	if err := run(t, pg, my, Ex04Unique01); !errors.Is(err, errInsert) {
+		t.Fatalf("err = %v", err)
+	}
+
+	checkTrace(t, "mysql", my, nil)
+}
+
+func TestEx04Upsert01(t *testing.T) {
+	pg, my := fakedb.NewScript(), fakedb.NewScript()
+	pg.Exec("ON CONFLICT").Result(0, 1)
+	my.Exec("ON DUPLICATE KEY").Result(5, 1).Once()
+	my.Exec("ON DUPLICATE KEY").Result(5, 2).Once()
+	my.Exec("ON DUPLICATE KEY").Result(5, 0).Once()
+	if err := run(t, pg, my, Ex04Upsert01); err != nil {
+		t.Fatal(err)
+	}
+
+	pgUpsert := "Exec INSERT INTO shop (name, updated_at) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET updated_at = EXCLUDED.updated_at"
+	myUpsert := "Exec INSERT INTO shop (name, updated_at) VALUES (?, ?) AS new ON DUPLICATE KEY UPDATE updated_at = new.updated_at"
+	checkTrace(t, "pg", pg, []string{pgUpsert, pgUpsert, pgUpsert})
+	checkTrace(t, "mysql", my, []string{myUpsert, myUpsert, myUpsert})
+}
+
+func TestEx04Upsert02(t *testing.T) {
+	tests := []struct {
+		dialect dialect.Dialect
+		script  func(*fakedb.Script)
+	}{
+		{
+			dialect: dialect.Postgres,
+			script: func(s *fakedb.Script) {
+				s.Query("ON CONFLICT").Rows([]string{"?column?"}, []driver.Value{true}).Once()
+				s.Query("ON CONFLICT").Rows([]string{"?column?"}, []driver.Value{false}).Once()
+				s.Query("ON CONFLICT").Rows([]string{"?column?"})
+			},
+		},
+		{
+			dialect: dialect.MySQL,
+			script: func(s *fakedb.Script) {
+				s.Exec("ON DUPLICATE KEY").Result(6, 1).Once()
+				s.Exec("ON DUPLICATE KEY").Result(6, 2).Once()
+				s.Exec("ON DUPLICATE KEY").Result(6, 0).Once()
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect.Name(), func(t *testing.T) {
+			s := fakedb.NewScript()
+			tt.script(s)
+			db := fakedb.Open(s)
+			defer db.Close()
+			out := golden.Capture(t)
+			if err := Ex04Upsert02(context.Background(), db, tt.dialect); err != nil {
+				t.Fatal(err)
+			}
+			out.Assert(t)
+			if got := len(s.Trace()); got != 3 {
+				t.Errorf("calls = %d, want 3", got)
+			}
+		})
+	}
+}
+
+// TestLive はgo test -live -run TestLive の場合だけ、docker-compose.ymlのデータベースで実行する
+func TestLive(t *testing.T) {
+	golden.Live(t, runner.Setup{Pg: setupPg, MySQL: setupMySQL})
+}
@@ -5,8 +5,19 @@ import (
 	"database/sql"
 	"errors"
 	"log/slog"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex04Tx01",
+		Target:      runner.Both,
+		Description: "BeginTxとCommitによる1相コミット",
+		Run:         runner.Pair(Ex04Tx01),
+	})
+}
+
 func Ex04Tx01(ctx context.Context, pgDB, myDB *sql.DB) error {
 	// PostgreSQL
 	pgConn, err := pgDB.Conn(ctx)
@@ -4,8 +4,19 @@ import (
 	"context"
 	"database/sql"
 	"log/slog"
+
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex04Tx02",
+		Target:      runner.Both,
+		Description: "ExecContextでトランザクションを制御する1相コミット",
+		Run:         runner.Pair(Ex04Tx02),
+	})
+}
+
 func Ex04Tx02(ctx context.Context, pgDB, myDB *sql.DB) error {
 	// PostgreSQL
 	pgConn, err := pgDB.Conn(ctx)
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+
+	"github.com/ystkg/db-examples/internal/dberr"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex04Unique01",
+		Target:      runner.Both,
+		Description: "一意制約違反をドライバに依存せずに判定する",
+		Run:         runner.Pair(Ex04Unique01),
+	})
+}
+
+func Ex04Unique01(ctx context.Context, pgDB, myDB *sql.DB) error {
+	// PostgreSQL
+	name := "shop5th"
+	for range 2 {
+		if err := ex04Unique01Insert(ctx, pgDB, "INSERT INTO shop (name) VALUES ($1)", name); err != nil {
+			return err
+		}
+	}
+
+	// MySQL（初期データに登録済み）
+	name = "shop1st"
+	if err := ex04Unique01Insert(ctx, myDB, "INSERT INTO shop (name) VALUES (?)", name); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func ex04Unique01Insert(ctx context.Context, db *sql.DB, query, name string) error {
+	result, err := db.ExecContext(ctx, query, name)
+	if err != nil {
+		e := dberr.Classify(err)
+		if e.Category != dberr.UniqueViolation {
+			return err
+		}
+		// PostgreSQLは23505（unique_violation）、MySQLは1062（ER_DUP_ENTRY）
+		slog.InfoContext(ctx, "INSERT", "name", name, "Category", e.Category.String(), "Code", e.Code)
+		return nil
+	}
+	rows, _ := result.RowsAffected()
+	slog.InfoContext(ctx, "INSERT", "name", name, "RowsAffected", rows)
+	return nil
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex04Upsert01",
+		Target:      runner.Both,
+		Description: "一意キーが重複したら更新するINSERTのRowsAffectedを比較する",
+		Run:         runner.Pair(Ex04Upsert01),
+	})
+}
+
+func Ex04Upsert01(ctx context.Context, pgDB, myDB *sql.DB) error {
+	// PostgreSQL
+	if err := ex04Upsert01Exec(ctx, pgDB,
+		"INSERT INTO shop (name, updated_at) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET updated_at = EXCLUDED.updated_at",
+	); err != nil {
+		return err
+	}
+
+	// MySQL
+	if err := ex04Upsert01Exec(ctx, myDB,
+		"INSERT INTO shop (name, updated_at) VALUES (?, ?) AS new ON DUPLICATE KEY UPDATE updated_at = new.updated_at",
+	); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func ex04Upsert01Exec(ctx context.Context, db *sql.DB, query string) error {
+	name := "shop5th"
+	now := time.Now().Truncate(time.Second) // MySQLのDATETIMEは秒単位
+
+	// 登録、別の値で更新、同じ値で更新の順に実行する
+	// PostgreSQLは全て1、MySQLは1、2、0になる
+	for _, updatedAt := range []time.Time{now, now.Add(time.Second), now.Add(time.Second)} {
+		result, err := db.ExecContext(ctx, query, name, updatedAt)
+		if err != nil {
+			return err
+		}
+		rows, _ := result.RowsAffected()
+		slog.InfoContext(ctx, "UPSERT", "RowsAffected", rows)
+	}
+
+	return nil
+}
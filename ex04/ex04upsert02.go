@@ -0,0 +1,42 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex04Upsert02",
+		Target:      runner.Either,
+		Description: "dialect.UpsertでINSERTとUPDATEのどちらになったかを判定する",
+		Run:         runner.Portable(Ex04Upsert02),
+	})
+}
+
+var shopUpsert = dialect.Upsert{
+	Table:   "shop",
+	Key:     []string{"name"},
+	Columns: []string{"name", "updated_at"},
+	Update:  []string{"updated_at"},
+}
+
+func Ex04Upsert02(ctx context.Context, db *sql.DB, d dialect.Dialect) error {
+	name := "shop6th"
+	now := time.Now().Truncate(time.Second) // MySQLのDATETIMEは秒単位
+
+	for _, updatedAt := range []time.Time{now, now.Add(time.Second), now.Add(time.Second)} {
+		outcome, err := shopUpsert.Exec(ctx, db, d, name, updatedAt)
+		if err != nil {
+			return err
+		}
+		slog.InfoContext(ctx, "UPSERT", "name", name, "outcome", outcome.String())
+	}
+
+	return nil
+}
@@ -3,14 +3,22 @@ package main
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 	"log/slog"
 
-	"github.com/go-sql-driver/mysql"
-	"github.com/jackc/pgconn"
+	"github.com/ystkg/db-examples/internal/dberr"
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex04Xa01",
+		Target:      runner.Both,
+		Description: "ExecContextで2相コミットを一括で制御する",
+		Run:         runner.Pair(Ex04Xa01),
+	})
+}
+
 func Ex04Xa01(ctx context.Context, pgDB, myDB *sql.DB) error {
 	// PostgreSQL
 	pgConn, err := pgDB.Conn(ctx)
@@ -66,8 +74,7 @@ func Ex04Xa01(ctx context.Context, pgDB, myDB *sql.DB) error {
 		if _, err := myConn.ExecContext(ctx,
 			fmt.Sprintf("XA ROLLBACK '%s'", transactionId),
 		); err != nil {
-			var myerr *mysql.MySQLError
-			if errors.As(err, &myerr); myerr.Number == 1397 {
+			if dberr.Is(dberr.Prepared(err), dberr.UnknownXID) {
 				// Error 1397 (XAE04): XAER_NOTA: Unknown XID
 				return
 			}
@@ -112,9 +119,8 @@ func Ex04Xa01(ctx context.Context, pgDB, myDB *sql.DB) error {
 		if _, err := pgConn.ExecContext(ctx,
 			fmt.Sprintf("rollback prepared '%s'", transactionId),
 		); err != nil {
-			var pgerr *pgconn.PgError
-			if errors.As(err, &pgerr); pgerr.Code == "42704" {
-				// transaction_id does not exist
+			if dberr.Is(dberr.Prepared(err), dberr.UnknownXID) {
+				// ERROR: prepared transaction with identifier "shop3rd2pc" does not exist (SQLSTATE 42704)
 				return
 			}
 			slog.WarnContext(ctx, "rollback prepared", "err", err)
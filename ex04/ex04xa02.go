@@ -3,13 +3,22 @@ package main
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 	"log/slog"
 
-	"github.com/go-sql-driver/mysql"
+	"github.com/ystkg/db-examples/internal/dberr"
+	"github.com/ystkg/db-examples/internal/runner"
 )
 
+func init() {
+	runner.Register(runner.Example{
+		Name:        "Ex04Xa02",
+		Target:      runner.Both,
+		Description: "コミット準備までを実行し、コミットは別に行う",
+		Run:         runner.Pair(Ex04Xa02),
+	})
+}
+
 func Ex04Xa02(ctx context.Context, pgDB, myDB *sql.DB) error {
 	err := ex04Xa02Pg(ctx, pgDB)
 	if err != nil {
@@ -105,8 +114,7 @@ func ex04Xa02MySQL(ctx context.Context, db *sql.DB) error {
 		if _, err := conn.ExecContext(ctx,
 			fmt.Sprintf("XA ROLLBACK '%s'", transactionId),
 		); err != nil {
-			var myerr *mysql.MySQLError
-			if errors.As(err, &myerr); myerr.Number == 1397 {
+			if dberr.Is(dberr.Prepared(err), dberr.UnknownXID) {
 				// Error 1397 (XAE04): XAER_NOTA: Unknown XID
 				return
 			}
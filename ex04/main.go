@@ -4,16 +4,20 @@ import (
 	"context"
 	"database/sql"
 	_ "embed"
+	"flag"
 	"fmt"
 	"log"
-	"log/slog"
-	"os"
-	"strings"
-	"time"
-
-	"github.com/go-sql-driver/mysql"
-	_ "github.com/jackc/pgx/v5/stdlib"
-	"gopkg.in/yaml.v3"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+var (
+	printConfigFlag = flag.Bool("print-config", false, "接続設定を表示して終了する")
+	timeoutFlag     = flag.Duration("timeout", runner.DefaultTimeout, "サンプル1つあたりのタイムアウト")
+	pgFlags         = dbsetup.RegisterFlags(flag.CommandLine, "pg")
+	startupFlags    = dbsetup.RegisterStartupFlags(flag.CommandLine)
+	mysqlFlags      = dbsetup.RegisterFlags(flag.CommandLine, "mysql")
 )
 
 var (
@@ -36,145 +40,71 @@ var (
 	pgclean string
 )
 
-func setup(ctx context.Context) (*sql.DB, *sql.DB, error) {
-	pgDB, err := setupPg(ctx)
-	if err != nil {
-		return nil, nil, err
-	}
-	myDB, err := setupMySQL(ctx)
+func pgConfig(driverName string) (dbsetup.Config, error) {
+	conf, err := dbsetup.LoadPostgres(yml)
 	if err != nil {
-		pgDB.Close()
-		return nil, nil, err
+		return dbsetup.Config{}, err
 	}
-	return pgDB, myDB, nil
-}
+	conf.Driver = driverName
+	conf.TimeZone = "Asia/Tokyo"
 
-func setupPg(ctx context.Context) (*sql.DB, error) {
-	conf := struct {
-		Services struct {
-			Postgres struct {
-				Ports       []string
-				Environment struct {
-					PostgresPassword string `yaml:"POSTGRES_PASSWORD"`
-				}
-			}
-		}
-	}{}
-	if err := yaml.Unmarshal(yml, &conf); err != nil {
-		return nil, err
-	}
+	return dbsetup.Resolve(conf, pgFlags)
+}
 
-	db, err := sql.Open("pgx",
-		fmt.Sprintf("postgres://postgres:%s@localhost:5432/postgres?sslmode=disable&TimeZone=Asia/Tokyo",
-			conf.Services.Postgres.Environment.PostgresPassword,
-		),
-	)
+func mysqlConfig() (dbsetup.Config, error) {
+	conf, err := dbsetup.LoadMySQL(yml)
 	if err != nil {
-		return nil, err
+		return dbsetup.Config{}, err
 	}
+	conf.TimeZone = "Asia/Tokyo"
 
-	_, err = db.ExecContext(ctx, pgclean)
-	if err != nil {
-		return nil, err
-	}
+	return dbsetup.Resolve(conf, mysqlFlags)
+}
 
-	_, err = db.ExecContext(ctx, pgddl)
+func setupPg(ctx context.Context, driverName string) (*sql.DB, error) {
+	conf, err := pgConfig(driverName)
 	if err != nil {
 		return nil, err
 	}
 
-	return db, nil
+	return dbsetup.OpenReset(ctx, conf, pgclean, pgddl)
 }
 
 func setupMySQL(ctx context.Context) (*sql.DB, error) {
-	conf := struct {
-		Services struct {
-			Mysql struct {
-				Environment struct {
-					MysqlRootPassword string `yaml:"MYSQL_ROOT_PASSWORD"`
-					MysqlDatabase     string `yaml:"MYSQL_DATABASE"`
-				}
-			}
-		}
-	}{}
-	if err := yaml.Unmarshal(yml, &conf); err != nil {
-		return nil, err
-	}
-
-	loc, err := time.LoadLocation("Asia/Tokyo")
-	if err != nil {
-		return nil, err
-	}
-
-	conn, err := mysql.NewConnector(&mysql.Config{
-		Addr:      "localhost:3306",
-		DBName:    conf.Services.Mysql.Environment.MysqlDatabase,
-		User:      "root",
-		Passwd:    conf.Services.Mysql.Environment.MysqlRootPassword,
-		ParseTime: true,
-		Loc:       loc,
-	})
+	conf, err := mysqlConfig()
 	if err != nil {
 		return nil, err
 	}
 
-	db := sql.OpenDB(conn)
-
-	_, err = db.ExecContext(ctx, mysqlclean)
-	if err != nil {
-		return nil, err
-	}
+	return dbsetup.OpenReset(ctx, conf, mysqlclean, mysqlddl, mysqldml)
+}
 
-	_, err = db.ExecContext(ctx, mysqlddl)
+func printConfig() error {
+	pgConf, err := pgConfig(dbsetup.DriverPgx)
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	_, err = db.ExecContext(ctx, mysqldml)
+	myConf, err := mysqlConfig()
 	if err != nil {
-		return nil, err
+		return err
 	}
-
-	return db, nil
+	fmt.Println(pgConf)
+	fmt.Println(myConf)
+	return nil
 }
 
 func main() {
-	if len(os.Args) < 2 {
-		log.Fatal("no name")
-	}
-	exname := os.Args[1]
-
-	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
-	defer cancel()
-
-	pgDB, myDB, err := setup(ctx)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer func() {
-		if err := pgDB.Close(); err != nil {
-			slog.WarnContext(ctx, "Close", "err", err)
-		}
-		if err := myDB.Close(); err != nil {
-			slog.WarnContext(ctx, "Close", "err", err)
+	flag.Parse()
+	if *printConfigFlag {
+		if err := printConfig(); err != nil {
+			log.Fatal(err)
 		}
-	}()
-
-	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
-
-	switch {
-	case strings.EqualFold(exname, "Ex04Tx01"):
-		err = Ex04Tx01(ctx, pgDB, myDB)
-	case strings.EqualFold(exname, "Ex04Tx02"):
-		err = Ex04Tx02(ctx, pgDB, myDB)
-	case strings.EqualFold(exname, "Ex04Xa01"):
-		err = Ex04Xa01(ctx, pgDB, myDB)
-	case strings.EqualFold(exname, "Ex04Xa02"):
-		err = Ex04Xa02(ctx, pgDB, myDB)
-	default:
-		err = fmt.Errorf("unknown:%s", exname)
+		return
 	}
-	if err != nil {
+
+	setup := runner.Setup{Pg: setupPg, MySQL: setupMySQL, Timeout: *timeoutFlag}
+	ctx := dbsetup.WithStartup(context.Background(), *startupFlags)
+	if err := runner.Main(ctx, flag.Args(), setup); err != nil {
 		log.Fatal(err)
 	}
 }
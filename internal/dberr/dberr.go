@@ -0,0 +1,172 @@
+// Package dberr はpgx、lib/pq、go-sql-driver/mysqlのエラーを共通の分類に変換する
+package dberr
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+)
+
+// Category はエラーの分類
+type Category int
+
+const (
+	Unknown              Category = iota
+	UniqueViolation               // 一意制約違反
+	ForeignKeyViolation           // 外部キー制約違反
+	SerializationFailure          // シリアライズ失敗。トランザクションを最初からやり直せば成功する可能性がある
+	Deadlock                      // デッドロック。トランザクションを最初からやり直せば成功する可能性がある
+	UnknownXID                    // 2相コミットのトランザクションIDが存在しない（PostgreSQLはPreparedで分類した場合のみ）
+	NotFound                      // 結果が0件（sql.ErrNoRows）
+	ConnectionLost                // 接続が切れた
+	Canceled                      // キャンセルかタイムアウト
+)
+
+var categoryNames = []string{
+	Unknown:              "Unknown",
+	UniqueViolation:      "UniqueViolation",
+	ForeignKeyViolation:  "ForeignKeyViolation",
+	SerializationFailure: "SerializationFailure",
+	Deadlock:             "Deadlock",
+	UnknownXID:           "UnknownXID",
+	NotFound:             "NotFound",
+	ConnectionLost:       "ConnectionLost",
+	Canceled:             "Canceled",
+}
+
+func (c Category) String() string {
+	if 0 <= c && int(c) < len(categoryNames) {
+		return categoryNames[c]
+	}
+	return fmt.Sprintf("Category(%d)", int(c))
+}
+
+// Error は分類したエラー
+type Error struct {
+	Category Category
+	Code     string // PostgreSQLはSQLSTATE、MySQLはエラー番号。ドライバのエラーでない場合は空
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s: %v", e.Category, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// PostgreSQLのSQLSTATE
+var pgCodes = map[string]Category{
+	"23505": UniqueViolation,
+	"23503": ForeignKeyViolation,
+	"40001": SerializationFailure,
+	"40P01": Deadlock,
+	"57014": Canceled, // query_canceled
+	"57P01": ConnectionLost,
+	"57P02": ConnectionLost,
+	"57P03": ConnectionLost,
+}
+
+// MySQLのエラー番号
+var myNumbers = map[uint16]Category{
+	1062: UniqueViolation, // ER_DUP_ENTRY
+	1451: ForeignKeyViolation,
+	1452: ForeignKeyViolation,
+	1213: Deadlock,   // ER_LOCK_DEADLOCK
+	1397: UnknownXID, // ER_XAER_NOTA
+	1317: Canceled,   // ER_QUERY_INTERRUPTED
+	3024: Canceled,   // ER_QUERY_TIMEOUT
+}
+
+// Classify はerrを分類する。errがnilの場合はnil
+func Classify(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return &Error{Category: pgCategory(pgErr.Code), Code: pgErr.Code, Err: err}
+	}
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return &Error{Category: pgCategory(string(pqErr.Code)), Code: string(pqErr.Code), Err: err}
+	}
+	var myErr *mysql.MySQLError
+	if errors.As(err, &myErr) {
+		return &Error{Category: myNumbers[myErr.Number], Code: strconv.Itoa(int(myErr.Number)), Err: err}
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.Is(err, sql.ErrNoRows):
+		return &Error{Category: NotFound, Err: err}
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return &Error{Category: Canceled, Err: err}
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, mysql.ErrInvalidConn),
+		errors.Is(err, io.EOF), errors.Is(err, io.ErrUnexpectedEOF), errors.Is(err, net.ErrClosed):
+		return &Error{Category: ConnectionLost, Err: err}
+	case errors.As(err, &netErr):
+		if netErr.Timeout() {
+			return &Error{Category: Canceled, Err: err}
+		}
+		return &Error{Category: ConnectionLost, Err: err}
+	}
+	return &Error{Category: Unknown, Err: err}
+}
+
+func pgCategory(code string) Category {
+	if c, ok := pgCodes[code]; ok {
+		return c
+	}
+	if strings.HasPrefix(code, "08") { // connection_exception
+		return ConnectionLost
+	}
+	return Unknown
+}
+
+// Prepared はROLLBACK PREPARED、COMMIT PREPARED、XA ROLLBACK、XA COMMITのエラーを分類する。errがnilの場合はnil
+// PostgreSQLの42704（undefined_object）は型やロールが存在しない場合にも使われるので、Classifyでは分類しない
+// 2相コミットの文では識別子のトランザクションが存在しないことを表すので、ここでUnknownXIDに分類する
+func Prepared(err error) error {
+	if err == nil {
+		return nil
+	}
+	e := Classify(err)
+	if e.Category == Unknown && e.Code == "42704" {
+		return &Error{Category: UnknownXID, Code: e.Code, Err: e.Err}
+	}
+	return e
+}
+
+// CategoryOf はerrの分類。errがnilの場合はUnknown
+func CategoryOf(err error) Category {
+	if err == nil {
+		return Unknown
+	}
+	return Classify(err).Category
+}
+
+// Is はerrがいずれかの分類に当たるかどうか
+func Is(err error, categories ...Category) bool {
+	if err == nil {
+		return false
+	}
+	return slices.Contains(categories, CategoryOf(err))
+}
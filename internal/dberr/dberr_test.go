@@ -0,0 +1,73 @@
+package dberr
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/lib/pq"
+)
+
+func TestClassify(t *testing.T) {
+	for _, tt := range []struct {
+		err      error
+		category Category
+		code     string
+	}{
+		{&pgconn.PgError{Code: "23505"}, UniqueViolation, "23505"},
+		{&pq.Error{Code: "23503"}, ForeignKeyViolation, "23503"},
+		{fmt.Errorf("commit: %w", &pgconn.PgError{Code: "40001"}), SerializationFailure, "40001"},
+		{&pq.Error{Code: "40P01"}, Deadlock, "40P01"},
+		{&pgconn.PgError{Code: "42704"}, Unknown, "42704"}, // undefined_objectは型やロールが存在しない場合も含む
+		{&pgconn.PgError{Code: "08006"}, ConnectionLost, "08006"},
+		{&pgconn.PgError{Code: "42601"}, Unknown, "42601"},
+		{&mysql.MySQLError{Number: 1062}, UniqueViolation, "1062"},
+		{&mysql.MySQLError{Number: 1452}, ForeignKeyViolation, "1452"},
+		{&mysql.MySQLError{Number: 1213}, Deadlock, "1213"},
+		{&mysql.MySQLError{Number: 1397}, UnknownXID, "1397"},
+		{sql.ErrNoRows, NotFound, ""},
+		{driver.ErrBadConn, ConnectionLost, ""},
+		{mysql.ErrInvalidConn, ConnectionLost, ""},
+		{context.DeadlineExceeded, Canceled, ""},
+		{errors.New("other"), Unknown, ""},
+	} {
+		e := Classify(tt.err)
+		if e.Category != tt.category || e.Code != tt.code || !errors.Is(e, tt.err) {
+			t.Errorf("Classify(%v) = %v, %q; want %v, %q", tt.err, e.Category, e.Code, tt.category, tt.code)
+		}
+	}
+
+	if Classify(nil) != nil || Is(nil, Unknown) {
+		t.Error("nil is classified")
+	}
+	if !Is(&mysql.MySQLError{Number: 1213}, SerializationFailure, Deadlock) {
+		t.Error("Is(1213, SerializationFailure, Deadlock) = false")
+	}
+}
+
+func TestPrepared(t *testing.T) {
+	for _, tt := range []struct {
+		err      error
+		category Category
+	}{
+		{&pgconn.PgError{Code: "42704"}, UnknownXID},
+		{&pq.Error{Code: "42704"}, UnknownXID},
+		{fmt.Errorf("rollback prepared: %w", &pgconn.PgError{Code: "42704"}), UnknownXID},
+		{&mysql.MySQLError{Number: 1397}, UnknownXID},
+		{&pgconn.PgError{Code: "57P01"}, ConnectionLost},
+		{errors.New("other"), Unknown},
+	} {
+		err := Prepared(tt.err)
+		if !Is(err, tt.category) || !errors.Is(err, tt.err) {
+			t.Errorf("Prepared(%v) = %v; want %v", tt.err, err, tt.category)
+		}
+	}
+	if Prepared(nil) != nil {
+		t.Error("nil is classified")
+	}
+}
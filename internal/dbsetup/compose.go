@@ -0,0 +1,154 @@
+package dbsetup
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// docker-compose.ymlのサービス名
+const (
+	ServicePostgres = "postgres"
+	ServiceMySQL    = "mysql"
+)
+
+var (
+	ErrServiceNotFound = errors.New("service not found")
+	ErrKeyNotFound     = errors.New("key not found")
+)
+
+// ComposeError はdocker-compose.ymlから設定を読み取れなかったことを表す
+type ComposeError struct {
+	Service string
+	Key     string
+	Err     error
+}
+
+func (e *ComposeError) Error() string {
+	if e.Key == "" {
+		return fmt.Sprintf("docker-compose.yml: %s: %v", e.Service, e.Err)
+	}
+	return fmt.Sprintf("docker-compose.yml: %s: %s: %v", e.Service, e.Key, e.Err)
+}
+
+func (e *ComposeError) Unwrap() error {
+	return e.Err
+}
+
+type composeService struct {
+	Ports       []string
+	Environment map[string]string
+}
+
+func loadService(yml []byte, name string) (*composeService, error) {
+	conf := struct {
+		Services map[string]*composeService
+	}{}
+	if err := yaml.Unmarshal(yml, &conf); err != nil {
+		return nil, err
+	}
+	service, ok := conf.Services[name]
+	if !ok || service == nil {
+		return nil, &ComposeError{Service: name, Err: ErrServiceNotFound}
+	}
+	return service, nil
+}
+
+func (s *composeService) env(service, key string) (string, error) {
+	v, ok := s.Environment[key]
+	if !ok || v == "" {
+		return "", &ComposeError{Service: service, Key: key, Err: ErrKeyNotFound}
+	}
+	return v, nil
+}
+
+// hostPort は "127.0.0.1:5432:5432" のようなポート定義からホスト側のアドレスを取り出す
+func (s *composeService) hostPort(service string, containerPort int) (string, int, error) {
+	suffix := ":" + strconv.Itoa(containerPort)
+	for _, p := range s.Ports {
+		if !strings.HasSuffix(p, suffix) {
+			continue
+		}
+		fields := strings.Split(strings.TrimSuffix(p, suffix), ":")
+		host := "localhost"
+		if len(fields) == 2 {
+			host = fields[0]
+		}
+		port, err := strconv.Atoi(fields[len(fields)-1])
+		if err != nil {
+			return "", 0, &ComposeError{Service: service, Key: "ports", Err: err}
+		}
+		return host, port, nil
+	}
+	return "", 0, &ComposeError{Service: service, Key: "ports", Err: ErrKeyNotFound}
+}
+
+// LoadPostgres はdocker-compose.ymlのpostgresサービスから接続設定を読み取る
+func LoadPostgres(yml []byte) (Config, error) {
+	service, err := loadService(yml, ServicePostgres)
+	if err != nil {
+		return Config{}, err
+	}
+	host, port, err := service.hostPort(ServicePostgres, 5432)
+	if err != nil {
+		return Config{}, err
+	}
+	password, err := service.env(ServicePostgres, "POSTGRES_PASSWORD")
+	if err != nil {
+		return Config{}, err
+	}
+	user := "postgres"
+	if v := service.Environment["POSTGRES_USER"]; v != "" {
+		user = v
+	}
+	database := user
+	if v := service.Environment["POSTGRES_DB"]; v != "" {
+		database = v
+	}
+	return Config{
+		Driver:   DriverPgx,
+		Host:     host,
+		Port:     port,
+		User:     user,
+		Password: password,
+		Database: database,
+		SSLMode:  "disable",
+	}, nil
+}
+
+// LoadMySQL はdocker-compose.ymlのmysqlサービスから接続設定を読み取る
+func LoadMySQL(yml []byte) (Config, error) {
+	service, err := loadService(yml, ServiceMySQL)
+	if err != nil {
+		return Config{}, err
+	}
+	host, port, err := service.hostPort(ServiceMySQL, 3306)
+	if err != nil {
+		return Config{}, err
+	}
+	database, err := service.env(ServiceMySQL, "MYSQL_DATABASE")
+	if err != nil {
+		return Config{}, err
+	}
+	user, password := "root", ""
+	if v := service.Environment["MYSQL_USER"]; v != "" {
+		user = v
+		password, err = service.env(ServiceMySQL, "MYSQL_PASSWORD")
+	} else {
+		password, err = service.env(ServiceMySQL, "MYSQL_ROOT_PASSWORD")
+	}
+	if err != nil {
+		return Config{}, err
+	}
+	return Config{
+		Driver:   DriverMySQL,
+		Host:     host,
+		Port:     port,
+		User:     user,
+		Password: password,
+		Database: database,
+	}, nil
+}
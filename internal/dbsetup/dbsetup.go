@@ -0,0 +1,211 @@
+// Package dbsetup は各サンプルで共通のデータベース接続と初期化を提供する
+package dbsetup
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/lib/pq"
+)
+
+// SQLドライバ名
+const (
+	DriverPgx   = "pgx"
+	DriverPq    = "postgres"
+	DriverMySQL = "mysql"
+)
+
+// Config は接続設定
+type Config struct {
+	Driver   string
+	Host     string
+	Port     int
+	User     string
+	Password string
+	Database string
+	SSLMode  string // PostgreSQLのsslmodeの値。MySQLではtlsパラメータに読み替える
+	Socket   string // Unixドメインソケット。指定するとHostとPortより優先（PostgreSQLはディレクトリ）
+	TimeZone string
+	Params   map[string]string // DSNに追加するランタイムパラメータ（PostgreSQLのみ）
+}
+
+// String はパスワードを伏せた設定内容
+func (c Config) String() string {
+	password := ""
+	if c.Password != "" {
+		password = "********"
+	}
+	return fmt.Sprintf("driver=%s host=%s port=%d socket=%s user=%s password=%s database=%s sslmode=%s timezone=%s",
+		c.Driver, c.Host, c.Port, c.Socket, c.User, password, c.Database, c.SSLMode, c.TimeZone,
+	)
+}
+
+// IsPostgres はPostgreSQLのドライバが選択されているかどうか
+func (c Config) IsPostgres() bool {
+	return c.Driver == DriverPgx || c.Driver == DriverPq
+}
+
+// IsMySQL はMySQLのドライバが選択されているかどうか
+func (c Config) IsMySQL() bool {
+	return c.Driver == DriverMySQL
+}
+
+// Addr は host:port 形式のアドレス
+func (c Config) Addr() string {
+	return net.JoinHostPort(c.Host, strconv.Itoa(c.Port))
+}
+
+// PostgresDSN はPostgreSQLのURL形式の接続文字列
+func (c Config) PostgresDSN() string {
+	query := url.Values{}
+	query.Set("sslmode", c.SSLMode)
+	if c.SSLMode == "" {
+		query.Set("sslmode", "disable")
+	}
+	host := c.Addr()
+	if c.Socket != "" {
+		host = ""
+		query.Set("host", c.Socket)
+		query.Set("port", strconv.Itoa(c.Port))
+	}
+	if c.TimeZone != "" {
+		query.Set("TimeZone", c.TimeZone)
+	}
+	for k, v := range c.Params {
+		query.Set(k, v)
+	}
+	u := url.URL{
+		Scheme:   "postgres",
+		User:     url.UserPassword(c.User, c.Password),
+		Host:     host,
+		Path:     "/" + c.Database,
+		RawQuery: query.Encode(),
+	}
+	return u.String()
+}
+
+// MySQLConfig はgo-sql-driver/mysqlの接続設定
+func (c Config) MySQLConfig() (*mysql.Config, error) {
+	conf := mysql.NewConfig()
+	conf.Net = "tcp"
+	conf.Addr = c.Addr()
+	if c.Socket != "" {
+		conf.Net = "unix"
+		conf.Addr = c.Socket
+	}
+	switch c.SSLMode {
+	case "":
+	case "disable":
+		conf.TLSConfig = "false"
+	case "allow", "prefer":
+		conf.TLSConfig = "preferred"
+	case "require":
+		conf.TLSConfig = "skip-verify"
+	case "verify-ca", "verify-full":
+		conf.TLSConfig = "true"
+	default:
+		// MySQLのssl-modeの値（REQUIREDなど）やtypoを既定のTLS設定で黙って接続しないようにする
+		return nil, fmt.Errorf("unknown sslmode:%s", c.SSLMode)
+	}
+	conf.DBName = c.Database
+	conf.User = c.User
+	conf.Passwd = c.Password
+	if c.TimeZone != "" {
+		loc, err := time.LoadLocation(c.TimeZone)
+		if err != nil {
+			return nil, err
+		}
+		conf.ParseTime = true
+		conf.Loc = loc
+	}
+	return conf, nil
+}
+
+// Connector は設定に従ってdriver.Connectorを生成する
+func Connector(c Config) (driver.Connector, error) {
+	switch c.Driver {
+	case DriverPgx:
+		return stdlib.GetDefaultDriver().(driver.DriverContext).OpenConnector(c.PostgresDSN())
+	case DriverPq:
+		return pq.NewConnector(c.PostgresDSN())
+	case DriverMySQL:
+		conf, err := c.MySQLConfig()
+		if err != nil {
+			return nil, err
+		}
+		return mysql.NewConnector(conf)
+	}
+	return nil, fmt.Errorf("unknown driver:%s", c.Driver)
+}
+
+type wrapperKey struct{}
+
+// WithWrapper はOpenで生成するConnectorをラップする関数をコンテキストに設定する
+// 各章のセットアップ処理を変えずに、ドライバの呼び出しを観測するために使う
+func WithWrapper(ctx context.Context, wrap func(driver.Connector) driver.Connector) context.Context {
+	if prev, ok := ctx.Value(wrapperKey{}).(func(driver.Connector) driver.Connector); ok {
+		inner := wrap
+		wrap = func(c driver.Connector) driver.Connector {
+			return inner(prev(c))
+		}
+	}
+	return context.WithValue(ctx, wrapperKey{}, wrap)
+}
+
+// Wrap はコンテキストに設定されたWithWrapperの関数でcをラップする。設定されていない場合はcのまま
+func Wrap(ctx context.Context, c driver.Connector) driver.Connector {
+	if wrap, ok := ctx.Value(wrapperKey{}).(func(driver.Connector) driver.Connector); ok {
+		return wrap(c)
+	}
+	return c
+}
+
+// Open は設定に従って*sql.DBを生成する
+func Open(ctx context.Context, c Config) (*sql.DB, error) {
+	conn, err := Connector(c)
+	if err != nil {
+		return nil, err
+	}
+	return sql.OpenDB(Wrap(ctx, conn)), nil
+}
+
+// Reset はクリーンアップやDDL、DMLのスクリプトを順に実行する
+func Reset(ctx context.Context, db *sql.DB, scripts ...string) error {
+	for _, script := range scripts {
+		if script == "" {
+			continue
+		}
+		if _, err := db.ExecContext(ctx, script); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// OpenReset はOpenとResetをまとめて実行する。Resetに失敗した場合はクローズする
+// WithStartupを設定した場合は、Resetの前に起動を待って事前に接続し、Resetの後にヘルスチェックを開始する
+func OpenReset(ctx context.Context, c Config, scripts ...string) (*sql.DB, error) {
+	s := startupFrom(ctx)
+	db, err := Open(s.wrap(ctx), c)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.run(ctx, db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	if err := Reset(ctx, db, scripts...); err != nil {
+		db.Close()
+		return nil, err
+	}
+	s.start()
+	return db, nil
+}
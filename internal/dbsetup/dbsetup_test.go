@@ -0,0 +1,47 @@
+package dbsetup
+
+import (
+	"testing"
+)
+
+func TestMySQLConfigSSLMode(t *testing.T) {
+	tests := []struct {
+		sslmode string
+		want    string
+	}{
+		{"", ""},
+		{"disable", "false"},
+		{"allow", "preferred"},
+		{"prefer", "preferred"},
+		{"require", "skip-verify"},
+		{"verify-ca", "true"},
+		{"verify-full", "true"},
+	}
+	for _, tt := range tests {
+		conf, err := Config{Driver: DriverMySQL, SSLMode: tt.sslmode}.MySQLConfig()
+		if err != nil {
+			t.Errorf("%q: %v", tt.sslmode, err)
+			continue
+		}
+		if conf.TLSConfig != tt.want {
+			t.Errorf("%q: %q", tt.sslmode, conf.TLSConfig)
+		}
+	}
+
+	// MySQLのssl-modeの名前やtypoはエラー
+	for _, sslmode := range []string{"DISABLED", "REQUIRED", "VERIFY_IDENTITY", "requre"} {
+		if _, err := (Config{Driver: DriverMySQL, SSLMode: sslmode}).MySQLConfig(); err == nil {
+			t.Errorf("%q: no error", sslmode)
+		}
+	}
+}
+
+func TestMySQLConfigSocket(t *testing.T) {
+	conf, err := Config{Driver: DriverMySQL, Host: "127.0.0.1", Port: 3306, Socket: "/var/run/mysqld/mysqld.sock"}.MySQLConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Net != "unix" || conf.Addr != "/var/run/mysqld/mysqld.sock" {
+		t.Errorf("%s %s", conf.Net, conf.Addr)
+	}
+}
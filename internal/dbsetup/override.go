@@ -0,0 +1,139 @@
+package dbsetup
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// 上書きに使う環境変数名
+type envNames struct {
+	host, port, socket, user, password, database, sslmode string
+}
+
+var (
+	pgEnv = envNames{
+		host:     "PGHOST", // "/" で始まる場合はソケットのディレクトリ
+		port:     "PGPORT",
+		user:     "PGUSER",
+		password: "PGPASSWORD",
+		database: "PGDATABASE",
+		sslmode:  "PGSSLMODE",
+	}
+	mysqlEnv = envNames{
+		host:     "MYSQL_HOST",
+		port:     "MYSQL_TCP_PORT",
+		socket:   "MYSQL_UNIX_PORT",
+		user:     "MYSQL_USER",
+		password: "MYSQL_PWD",
+		database: "MYSQL_DATABASE",
+		sslmode:  "MYSQL_SSLMODE",
+	}
+)
+
+// sslModes はSSLModeに指定できる値（PostgreSQLのsslmodeの名前。MySQLでも同じ名前で指定する）
+var sslModes = []string{"disable", "allow", "prefer", "require", "verify-ca", "verify-full"}
+
+// Flags はコマンドライン引数による上書き
+type Flags struct {
+	Host     string
+	Port     int
+	Socket   string
+	User     string
+	Database string
+	SSLMode  string
+}
+
+// RegisterFlags は -<prefix>-host などのフラグを登録する
+func RegisterFlags(fs *flag.FlagSet, prefix string) *Flags {
+	f := &Flags{}
+	fs.StringVar(&f.Host, prefix+"-host", "", "ホスト名")
+	fs.IntVar(&f.Port, prefix+"-port", 0, "ポート番号")
+	fs.StringVar(&f.Socket, prefix+"-socket", "", "Unixドメインソケットのパス")
+	fs.StringVar(&f.User, prefix+"-user", "", "ユーザー名")
+	fs.StringVar(&f.Database, prefix+"-database", "", "データベース名")
+	fs.StringVar(&f.SSLMode, prefix+"-sslmode", "", "sslmode（disable, require, verify-fullなど）")
+	return f
+}
+
+func (f *Flags) apply(c *Config) {
+	if f == nil {
+		return
+	}
+	if f.Host != "" {
+		c.Host = f.Host
+	}
+	if f.Port != 0 {
+		c.Port = f.Port
+	}
+	if f.Socket != "" {
+		c.Socket = f.Socket
+	}
+	if f.User != "" {
+		c.User = f.User
+	}
+	if f.Database != "" {
+		c.Database = f.Database
+	}
+	if f.SSLMode != "" {
+		c.SSLMode = f.SSLMode
+	}
+}
+
+func applyEnv(c *Config, names envNames) error {
+	get := func(key string) string {
+		if key == "" {
+			return ""
+		}
+		return os.Getenv(key)
+	}
+	if v := get(names.host); v != "" {
+		if c.IsPostgres() && strings.HasPrefix(v, "/") {
+			c.Socket = v
+		} else {
+			c.Host = v
+		}
+	}
+	if v := get(names.port); v != "" {
+		port, err := strconv.Atoi(v)
+		if err != nil {
+			return fmt.Errorf("%s: %w", names.port, err)
+		}
+		c.Port = port
+	}
+	if v := get(names.socket); v != "" {
+		c.Socket = v
+	}
+	if v := get(names.user); v != "" {
+		c.User = v
+	}
+	if v := get(names.password); v != "" {
+		c.Password = v
+	}
+	if v := get(names.database); v != "" {
+		c.Database = v
+	}
+	if v := get(names.sslmode); v != "" {
+		c.SSLMode = v
+	}
+	return nil
+}
+
+// Resolve はdocker-compose.ymlの設定を環境変数、コマンドライン引数の順で上書きする
+func Resolve(c Config, f *Flags) (Config, error) {
+	names := mysqlEnv
+	if c.IsPostgres() {
+		names = pgEnv
+	}
+	if err := applyEnv(&c, names); err != nil {
+		return Config{}, err
+	}
+	f.apply(&c)
+	if c.SSLMode != "" && !slices.Contains(sslModes, c.SSLMode) {
+		return Config{}, fmt.Errorf("unknown sslmode:%s", c.SSLMode)
+	}
+	return c, nil
+}
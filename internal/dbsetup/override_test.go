@@ -0,0 +1,107 @@
+package dbsetup
+
+import (
+	"testing"
+)
+
+// clearEnv はテストを実行する環境の変数の影響を受けないように上書きに使う環境変数を空にする
+func clearEnv(t *testing.T) {
+	t.Helper()
+	for _, names := range []envNames{pgEnv, mysqlEnv} {
+		for _, key := range []string{names.host, names.port, names.socket, names.user, names.password, names.database, names.sslmode} {
+			if key != "" {
+				t.Setenv(key, "")
+			}
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	compose := Config{Driver: DriverPgx, Host: "127.0.0.1", Port: 5432, User: "postgres", Password: "expasswd", Database: "postgres", SSLMode: "disable"}
+	tests := []struct {
+		name  string
+		env   map[string]string
+		flags *Flags
+		want  Config
+	}{
+		{"compose", nil, nil, compose},
+		{
+			"env",
+			map[string]string{"PGHOST": "db.example.com", "PGPORT": "15432", "PGUSER": "exuser", "PGPASSWORD": "secret", "PGDATABASE": "exdb", "PGSSLMODE": "require"},
+			nil,
+			Config{Driver: DriverPgx, Host: "db.example.com", Port: 15432, User: "exuser", Password: "secret", Database: "exdb", SSLMode: "require"},
+		},
+		{
+			"flag",
+			map[string]string{"PGHOST": "db.example.com", "PGPORT": "15432", "PGUSER": "exuser"},
+			&Flags{Host: "flag.example.com", Database: "flagdb", SSLMode: "verify-full"},
+			Config{Driver: DriverPgx, Host: "flag.example.com", Port: 15432, User: "exuser", Password: "expasswd", Database: "flagdb", SSLMode: "verify-full"},
+		},
+		{
+			"socket",
+			map[string]string{"PGHOST": "/var/run/postgresql"},
+			nil,
+			Config{Driver: DriverPgx, Host: "127.0.0.1", Port: 5432, User: "postgres", Password: "expasswd", Database: "postgres", SSLMode: "disable", Socket: "/var/run/postgresql"},
+		},
+		{
+			"socket flag",
+			map[string]string{"PGHOST": "/var/run/postgresql"},
+			&Flags{Socket: "/tmp"},
+			Config{Driver: DriverPgx, Host: "127.0.0.1", Port: 5432, User: "postgres", Password: "expasswd", Database: "postgres", SSLMode: "disable", Socket: "/tmp"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			got, err := Resolve(compose, tt.flags)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.String() != tt.want.String() || got.Password != tt.want.Password {
+				t.Errorf("\n got: %v\nwant: %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResolveMySQL(t *testing.T) {
+	clearEnv(t)
+	// PGHOSTはMySQLには影響しない。MySQLのソケットはMYSQL_UNIX_PORT
+	t.Setenv("PGHOST", "/var/run/postgresql")
+	t.Setenv("MYSQL_HOST", "/not/a/socket")
+	t.Setenv("MYSQL_UNIX_PORT", "/var/run/mysqld/mysqld.sock")
+
+	got, err := Resolve(Config{Driver: DriverMySQL, Host: "127.0.0.1", Port: 3306}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Host != "/not/a/socket" || got.Socket != "/var/run/mysqld/mysqld.sock" {
+		t.Errorf("%v", got)
+	}
+}
+
+func TestResolveError(t *testing.T) {
+	tests := []struct {
+		name  string
+		env   map[string]string
+		flags *Flags
+	}{
+		{"port", map[string]string{"PGPORT": "x"}, nil},
+		{"sslmode env", map[string]string{"PGSSLMODE": "REQUIRED"}, nil},
+		{"sslmode flag", nil, &Flags{SSLMode: "verify_identity"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clearEnv(t)
+			for k, v := range tt.env {
+				t.Setenv(k, v)
+			}
+			if _, err := Resolve(Config{Driver: DriverPgx}, tt.flags); err == nil {
+				t.Error("no error")
+			}
+		})
+	}
+}
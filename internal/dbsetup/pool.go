@@ -0,0 +1,56 @@
+package dbsetup
+
+import (
+	"database/sql"
+	"flag"
+	"fmt"
+	"time"
+)
+
+// Pool はコネクションプールの設定。0の項目は変更しない（database/sqlのデフォルトのまま）
+type Pool struct {
+	MaxOpen     int           // SetMaxOpenConns
+	MaxIdle     int           // SetMaxIdleConns。負の値はIdleのコネクションを保持しない
+	MaxLifetime time.Duration // SetConnMaxLifetime。サーバ側のタイムアウトより短くする
+	MaxIdleTime time.Duration // SetConnMaxIdleTime
+}
+
+// String は設定内容。0の項目は "-"
+func (p Pool) String() string {
+	v := func(set bool, s any) any {
+		if !set {
+			return "-"
+		}
+		return s
+	}
+	return fmt.Sprintf("maxOpen=%v maxIdle=%v maxLifetime=%v maxIdleTime=%v",
+		v(p.MaxOpen != 0, p.MaxOpen), v(p.MaxIdle != 0, p.MaxIdle),
+		v(p.MaxLifetime != 0, p.MaxLifetime), v(p.MaxIdleTime != 0, p.MaxIdleTime),
+	)
+}
+
+// Apply は設定をdbに反映する
+func (p Pool) Apply(db *sql.DB) {
+	if p.MaxOpen != 0 {
+		db.SetMaxOpenConns(p.MaxOpen)
+	}
+	if p.MaxIdle != 0 {
+		db.SetMaxIdleConns(p.MaxIdle)
+	}
+	if p.MaxLifetime != 0 {
+		db.SetConnMaxLifetime(p.MaxLifetime)
+	}
+	if p.MaxIdleTime != 0 {
+		db.SetConnMaxIdleTime(p.MaxIdleTime)
+	}
+}
+
+// RegisterPoolFlags は -<prefix>-max-open などのフラグを登録する
+func RegisterPoolFlags(fs *flag.FlagSet, prefix string) *Pool {
+	p := &Pool{}
+	fs.IntVar(&p.MaxOpen, prefix+"-max-open", 0, "SetMaxOpenConns（0は変更しない）")
+	fs.IntVar(&p.MaxIdle, prefix+"-max-idle", 0, "SetMaxIdleConns（0は変更しない、負の値はIdleを保持しない）")
+	fs.DurationVar(&p.MaxLifetime, prefix+"-max-lifetime", 0, "SetConnMaxLifetime（0は変更しない）")
+	fs.DurationVar(&p.MaxIdleTime, prefix+"-max-idle-time", 0, "SetConnMaxIdleTime（0は変更しない）")
+	return p
+}
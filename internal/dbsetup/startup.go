@@ -0,0 +1,177 @@
+package dbsetup
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/health"
+)
+
+// -waitと再試行の間隔のデフォルト
+// 起動を待つのはサンプルのタイムアウト（runner.DefaultTimeoutは10秒）の中なので、-waitはそれより十分に短くする
+const (
+	DefaultWait       = 5 * time.Second
+	DefaultBackoff    = 100 * time.Millisecond
+	DefaultMaxBackoff = 2 * time.Second
+)
+
+// Startup は起動時の処理。OpenResetでResetの前に実行する
+type Startup struct {
+	Wait       time.Duration // データベースの起動を待つ時間の上限。0の場合は待たない
+	Backoff    time.Duration // 最初の再試行までの間隔。失敗する毎に2倍にする。0の場合はDefaultBackoff
+	MaxBackoff time.Duration // 再試行の間隔の上限。0の場合はDefaultMaxBackoff
+	Warm       int           // 事前に接続しておくコネクション数
+	Health     time.Duration // ヘルスチェックの間隔。0の場合はチェックしない
+}
+
+// RegisterStartupFlags は -wait などのフラグを登録する
+func RegisterStartupFlags(fs *flag.FlagSet) *Startup {
+	s := &Startup{}
+	fs.DurationVar(&s.Wait, "wait", DefaultWait, "データベースの起動を待つ時間の上限（0は待たない。-timeoutより短くする）")
+	fs.DurationVar(&s.Backoff, "wait-backoff", DefaultBackoff, "起動を待つ間の最初の再試行までの間隔")
+	fs.IntVar(&s.Warm, "warm", 0, "事前に接続しておくコネクション数")
+	fs.DurationVar(&s.Health, "health", 0, "PingContextによるヘルスチェックの間隔（0はチェックしない）")
+	return s
+}
+
+type startupKey struct{}
+
+// WithStartup はOpenResetで実行する起動時の処理をコンテキストに設定する
+func WithStartup(ctx context.Context, s Startup) context.Context {
+	return context.WithValue(ctx, startupKey{}, s)
+}
+
+// WaitReady はPingContextが成功するまで間隔を広げながら再試行する
+// s.Waitを過ぎた場合（ctxの期限が先に来た場合を含む）は、待っていたことと最後のエラーを返す
+func WaitReady(ctx context.Context, db *sql.DB, s Startup) error {
+	start := time.Now()
+	err := db.PingContext(ctx)
+	if err == nil || s.Wait <= 0 {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.Wait)
+	defer cancel()
+
+	backoff := s.Backoff
+	if backoff <= 0 {
+		backoff = DefaultBackoff
+	}
+	maxBackoff := s.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+	for {
+		slog.InfoContext(ctx, "waiting", "err", err, "retry", backoff)
+		t := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return fmt.Errorf("dbsetup: database is not ready after waiting %s: %w", time.Since(start).Round(time.Millisecond), err)
+		case <-t.C:
+		}
+		if err = db.PingContext(ctx); err == nil {
+			return nil
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// Warm はn個のコネクションを接続してIdleにしておく
+// database/sqlのデフォルトではIdleは2つまでなので、nが多い場合はSetMaxIdleConnsも変更する
+func Warm(ctx context.Context, db *sql.DB, n int) error {
+	if n <= 0 {
+		return nil
+	}
+	if 2 < n {
+		db.SetMaxIdleConns(n)
+	}
+	conns := make([]*sql.Conn, 0, n)
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	for range n {
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		conns = append(conns, conn)
+	}
+	return nil
+}
+
+var checkers sync.Map // *sql.DB -> *health.Checker
+
+// Health はOpenResetで開始したヘルスチェック。Startup.Healthが0の場合はfalse
+func Health(db *sql.DB) (*health.Checker, bool) {
+	c, ok := checkers.Load(db)
+	if !ok {
+		return nil, false
+	}
+	return c.(*health.Checker), true
+}
+
+// startup はコンテキストに設定された起動時の処理
+type startup struct {
+	Startup
+	checker *health.Checker
+	db      *sql.DB
+}
+
+func startupFrom(ctx context.Context) *startup {
+	s, _ := ctx.Value(startupKey{}).(Startup)
+	return &startup{Startup: s}
+}
+
+// wrap はヘルスチェックを使う場合にConnectorのラップをコンテキストに追加する
+func (s *startup) wrap(ctx context.Context) context.Context {
+	if s.Health <= 0 {
+		return ctx
+	}
+	s.checker = health.New(s.Health)
+	return WithWrapper(ctx, func(c driver.Connector) driver.Connector {
+		return &unregister{Connector: s.checker.Wrap(c), startup: s}
+	})
+}
+
+// run は起動を待ち、事前に接続する。Waitが0の場合は待たない（最初のクエリで接続する）
+func (s *startup) run(ctx context.Context, db *sql.DB) error {
+	s.db = db
+	if 0 < s.Wait {
+		if err := WaitReady(ctx, db, s.Startup); err != nil {
+			return err
+		}
+	}
+	return Warm(ctx, db, s.Warm)
+}
+
+// start はヘルスチェックを開始する
+func (s *startup) start() {
+	if s.checker == nil {
+		return
+	}
+	checkers.Store(s.db, s.checker)
+	s.checker.Start(s.db)
+}
+
+// unregister はDB.Closeの時点でHealthの対象から外す
+type unregister struct {
+	driver.Connector
+	startup *startup
+}
+
+func (u *unregister) Close() error {
+	checkers.Delete(u.startup.db)
+	if closer, ok := u.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
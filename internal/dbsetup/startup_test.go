@@ -0,0 +1,141 @@
+package dbsetup
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+var errDown = errors.New("connection refused")
+
+func countConnect(s *fakedb.Script) int {
+	n := 0
+	for _, c := range s.Calls() {
+		if c.Op == fakedb.OpConnect {
+			n++
+		}
+	}
+	return n
+}
+
+func TestWaitReady(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Connect().Err(errDown).Times(3)
+	db := fakedb.Open(s)
+	defer db.Close()
+
+	start := time.Now()
+	err := WaitReady(context.Background(), db, Startup{Wait: time.Second, Backoff: 10 * time.Millisecond, MaxBackoff: 20 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 10ms、20ms、20ms（MaxBackoffで頭打ち）の間隔で再試行する
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("elapsed = %v", elapsed)
+	}
+	if n := countConnect(s); n != 4 {
+		t.Errorf("Connect = %d", n)
+	}
+}
+
+func TestWaitReadyDeadline(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Connect().Err(errDown)
+	db := fakedb.Open(s)
+	defer db.Close()
+
+	start := time.Now()
+	err := WaitReady(context.Background(), db, Startup{Wait: 50 * time.Millisecond, Backoff: 10 * time.Millisecond})
+	if !errors.Is(err, errDown) || !strings.Contains(err.Error(), "database is not ready after waiting") {
+		t.Errorf("err = %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || time.Second < elapsed {
+		t.Errorf("elapsed = %v", elapsed)
+	}
+	// 0, 10, 30ms（10msの後に2倍の20ms）で試行し、次の40msの待ちの途中で期限になる
+	if n := countConnect(s); n != 3 {
+		t.Errorf("Connect = %d", n)
+	}
+
+	// サンプルのタイムアウトが先に来た場合も待っていたことが分かるエラーにする
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	err = WaitReady(ctx, db, Startup{Wait: time.Second, Backoff: 10 * time.Millisecond})
+	if !errors.Is(err, errDown) || !strings.Contains(err.Error(), "database is not ready after waiting") {
+		t.Errorf("err = %v", err)
+	}
+
+	// Waitが0の場合は1回だけ試行する
+	s.Reset()
+	if err := WaitReady(context.Background(), db, Startup{}); !errors.Is(err, errDown) {
+		t.Errorf("err = %v", err)
+	}
+	if n := countConnect(s); n != 1 {
+		t.Errorf("Connect = %d", n)
+	}
+}
+
+func TestWarm(t *testing.T) {
+	tests := []struct {
+		n       int
+		connect int
+		idle    int
+	}{
+		{0, 0, 0},
+		{2, 2, 2},
+		{5, 5, 5}, // デフォルトのMaxIdleConns（2）を超える分はSetMaxIdleConnsで保持する
+	}
+	for _, tt := range tests {
+		s := fakedb.NewScript()
+		db := fakedb.Open(s)
+		if err := Warm(context.Background(), db, tt.n); err != nil {
+			t.Fatal(err)
+		}
+		stats := db.Stats()
+		if n := countConnect(s); n != tt.connect || stats.Idle != tt.idle || stats.InUse != 0 {
+			t.Errorf("%d: Connect = %d, %+v", tt.n, n, stats)
+		}
+		db.Close()
+	}
+}
+
+func TestWarmError(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Connect().Once() // 1つ目は接続できる
+	s.Connect().Err(errDown).Once()
+	db := fakedb.Open(s)
+	defer db.Close()
+
+	// 2つ目の接続に失敗した場合は、取得済みのものを返却してエラーを返す
+	if err := Warm(context.Background(), db, 3); !errors.Is(err, errDown) {
+		t.Errorf("err = %v", err)
+	}
+	if stats := db.Stats(); stats.InUse != 0 || stats.Idle != 1 {
+		t.Errorf("%+v", stats)
+	}
+}
+
+func TestStartupHealth(t *testing.T) {
+	s := fakedb.NewScript()
+	st := startupFrom(WithStartup(context.Background(), Startup{Wait: time.Second, Warm: 1, Health: time.Hour}))
+	db := sql.OpenDB(Wrap(st.wrap(context.Background()), fakedb.NewConnector(s)))
+	if err := st.run(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	st.start()
+
+	checker, ok := Health(db)
+	if !ok || checker != st.checker {
+		t.Fatal("no checker")
+	}
+	// DB.Closeの時点で対象から外す
+	db.Close()
+	if _, ok := Health(db); ok {
+		t.Error("checker after Close")
+	}
+}
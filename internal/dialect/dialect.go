@@ -0,0 +1,202 @@
+// Package dialect はPostgreSQLとMySQLのSQLの書き方の違いを吸収する
+package dialect
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+)
+
+// Capability はデータベースによって対応が異なる機能
+type Capability int
+
+const (
+	Returning      Capability = iota // INSERT/UPDATE/DELETEのRETURNING
+	LastInsertId                     // sql.ResultのLastInsertId
+	OnConflict                       // INSERT ... ON CONFLICT DO UPDATE
+	OnDuplicateKey                   // INSERT ... ON DUPLICATE KEY UPDATE
+)
+
+func (c Capability) String() string {
+	switch c {
+	case Returning:
+		return "Returning"
+	case LastInsertId:
+		return "LastInsertId"
+	case OnConflict:
+		return "OnConflict"
+	case OnDuplicateKey:
+		return "OnDuplicateKey"
+	}
+	return fmt.Sprintf("Capability(%d)", int(c))
+}
+
+// Dialect はデータベース毎のSQLの書き方
+type Dialect interface {
+	// Name はデータベースの名前
+	Name() string
+	// Placeholder はn番目（1始まり）のプレースホルダ
+	Placeholder(n int) string
+	// In はstart番目からn個のプレースホルダを括弧で囲んだIN句のリスト
+	In(start, n int) string
+	// Rebind は ? で書いたプレースホルダをこのデータベースの書き方に置き換える
+	// 文字列リテラル、引用符で囲んだ識別子、コメントの中の ? は置き換えない
+	Rebind(query string) string
+	// QuoteIdent は識別子を引用符で囲む。"." で区切られた修飾名はそれぞれを囲む
+	QuoteIdent(name string) string
+	// Supports は機能に対応しているかどうか
+	Supports(c Capability) bool
+	// MaxParams は1つのSQLに指定できるパラメータの上限
+	MaxParams() int
+
+	// identQuote は識別子を囲む引用符
+	identQuote() byte
+}
+
+var (
+	Postgres Dialect = postgres{}
+	MySQL    Dialect = mysql{}
+)
+
+// For はSQLドライバ名に対応するDialect
+func For(driverName string) (Dialect, error) {
+	switch driverName {
+	case dbsetup.DriverPgx, dbsetup.DriverPq:
+		return Postgres, nil
+	case dbsetup.DriverMySQL:
+		return MySQL, nil
+	}
+	return nil, fmt.Errorf("dialect: unknown driver:%s", driverName)
+}
+
+type postgres struct{}
+
+func (postgres) Name() string {
+	return "postgres"
+}
+
+func (postgres) Placeholder(n int) string {
+	return "$" + strconv.Itoa(n)
+}
+
+func (d postgres) In(start, n int) string {
+	return in(d, start, n)
+}
+
+func (d postgres) Rebind(query string) string {
+	return rebind(d, query)
+}
+
+func (d postgres) QuoteIdent(name string) string {
+	return quoteIdent(name, d.identQuote())
+}
+
+func (postgres) Supports(c Capability) bool {
+	return c == Returning || c == OnConflict
+}
+
+func (postgres) MaxParams() int {
+	return 65535 // プロトコルのパラメータ数が16bit
+}
+
+func (postgres) identQuote() byte {
+	return '"'
+}
+
+type mysql struct{}
+
+func (mysql) Name() string {
+	return "mysql"
+}
+
+func (mysql) Placeholder(int) string {
+	return "?"
+}
+
+func (d mysql) In(start, n int) string {
+	return in(d, start, n)
+}
+
+func (mysql) Rebind(query string) string {
+	return query
+}
+
+func (d mysql) QuoteIdent(name string) string {
+	return quoteIdent(name, d.identQuote())
+}
+
+func (mysql) Supports(c Capability) bool {
+	return c == LastInsertId || c == OnDuplicateKey
+}
+
+func (mysql) MaxParams() int {
+	return 65535 // プリペアドステートメントのパラメータ数が16bit
+}
+
+func (mysql) identQuote() byte {
+	return '`'
+}
+
+func in(d Dialect, start, n int) string {
+	ph := make([]string, n)
+	for i := range ph {
+		ph[i] = d.Placeholder(start + i)
+	}
+	return "(" + strings.Join(ph, ",") + ")"
+}
+
+func quoteIdent(name string, q byte) string {
+	parts := strings.Split(name, ".")
+	for i, p := range parts {
+		s := string(q)
+		parts[i] = s + strings.ReplaceAll(p, s, s+s) + s
+	}
+	return strings.Join(parts, ".")
+}
+
+// rebind は ? を順にPlaceholderに置き換える
+func rebind(d Dialect, query string) string {
+	var b strings.Builder
+	prev := 0
+	for n, i := range placeholders(query, d.identQuote()) {
+		b.WriteString(query[prev:i])
+		b.WriteString(d.Placeholder(n + 1))
+		prev = i + 1
+	}
+	b.WriteString(query[prev:])
+	return b.String()
+}
+
+// placeholders はプレースホルダとして扱う ? の位置
+// 文字列リテラル、引用符で囲んだ識別子、コメントの中の ? は含めない
+// 引用符は2つ重ねてエスケープするので、閉じた直後に再び開くと見なしてよい
+// バックスラッシュによるエスケープは考慮しない
+func placeholders(query string, identQuote byte) []int {
+	var pos []int
+	for i := 0; i < len(query); {
+		j := i + 1 // 次に読む位置
+		switch c := query[i]; {
+		case c == '\'' || c == identQuote:
+			j = skipPast(query, i+1, string(c))
+		case strings.HasPrefix(query[i:], "--"):
+			j = skipPast(query, i+2, "\n")
+		case strings.HasPrefix(query[i:], "/*"):
+			j = skipPast(query, i+2, "*/")
+		case c == '?':
+			pos = append(pos, i)
+		}
+		i = j
+	}
+	return pos
+}
+
+// skipPast はquery[i:]でendが現れた直後の位置。現れなければ末尾
+func skipPast(query string, i int, end string) int {
+	k := strings.Index(query[i:], end)
+	if k < 0 {
+		return len(query)
+	}
+	return i + k + len(end)
+}
@@ -0,0 +1,40 @@
+package dialect
+
+import "testing"
+
+func TestRebind(t *testing.T) {
+	for _, tt := range []struct {
+		query string
+		want  string
+	}{
+		{"SELECT * FROM movie WHERE id = ? AND title = ?", "SELECT * FROM movie WHERE id = $1 AND title = $2"},
+		{"SELECT '?', 'it''s ?', \"a?\" FROM t WHERE id = ?", "SELECT '?', 'it''s ?', \"a?\" FROM t WHERE id = $1"},
+		{"SELECT ? -- ?\n, ? /* ? */ , ?", "SELECT $1 -- ?\n, $2 /* ? */ , $3"},
+		{"SELECT 'unterminated ?", "SELECT 'unterminated ?"},
+	} {
+		if got := Postgres.Rebind(tt.query); got != tt.want {
+			t.Errorf("Rebind(%q) = %q, want %q", tt.query, got, tt.want)
+		}
+		if got := MySQL.Rebind(tt.query); got != tt.query {
+			t.Errorf("MySQL.Rebind(%q) = %q", tt.query, got)
+		}
+	}
+}
+
+func TestIn(t *testing.T) {
+	if got := Postgres.In(3, 3); got != "($3,$4,$5)" {
+		t.Errorf("Postgres.In = %q", got)
+	}
+	if got := MySQL.In(3, 3); got != "(?,?,?)" {
+		t.Errorf("MySQL.In = %q", got)
+	}
+}
+
+func TestQuoteIdent(t *testing.T) {
+	if got := Postgres.QuoteIdent(`public.my"table`); got != `"public"."my""table"` {
+		t.Errorf("Postgres.QuoteIdent = %s", got)
+	}
+	if got := MySQL.QuoteIdent("db.my`table"); got != "`db`.`my``table`" {
+		t.Errorf("MySQL.QuoteIdent = %s", got)
+	}
+}
@@ -0,0 +1,91 @@
+package dialect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// InList はExpandInとExecInのテンプレートでIN句のリストを展開する位置
+//
+//	DELETE FROM movie WHERE id IN (?...) AND updated_at < ?
+const InList = "(?...)"
+
+// Statement は実行するSQLとパラメータ
+type Statement struct {
+	Query string
+	Args  []any
+}
+
+// Execer は*sql.DB、*sql.Conn、*sql.Tx
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// ExpandIn はテンプレートのInListをlistの要素数分のプレースホルダに展開する
+//
+// テンプレートは ? で書き、argsはInList以外の ? に順に対応する
+// パラメータの数がMaxParamsを超える場合はlistを分割して複数のStatementにする
+// listが空の場合はStatementを返さない（IN () は構文エラーになるため）
+func ExpandIn[T any](d Dialect, query string, list []T, args ...any) ([]Statement, error) {
+	at := strings.Index(query, InList)
+	if at < 0 {
+		return nil, fmt.Errorf("dialect: no %s in query", InList)
+	}
+	before, after := query[:at], query[at+len(InList):]
+	if strings.Contains(after, InList) {
+		return nil, fmt.Errorf("dialect: multiple %s in query", InList)
+	}
+
+	// InListより前の ? の数で、argsをInListの前後に分ける
+	nBefore := len(placeholders(before, d.identQuote()))
+	if len(args) < nBefore {
+		return nil, errors.New("dialect: not enough args")
+	}
+	size := d.MaxParams() - len(args)
+	if size <= 0 {
+		return nil, fmt.Errorf("dialect: too many args:%d", len(args))
+	}
+
+	var stmts []Statement
+	for chunk := range slices.Chunk(list, size) {
+		stmtArgs := make([]any, 0, len(args)+len(chunk))
+		stmtArgs = append(stmtArgs, args[:nBefore]...)
+		for _, v := range chunk {
+			stmtArgs = append(stmtArgs, v)
+		}
+		stmtArgs = append(stmtArgs, args[nBefore:]...)
+		stmts = append(stmts, Statement{
+			Query: d.Rebind(before + "(" + strings.Repeat("?,", len(chunk)-1) + "?)" + after),
+			Args:  stmtArgs,
+		})
+	}
+	return stmts, nil
+}
+
+// ExecIn はExpandInで展開したSQLを順に実行し、RowsAffectedの合計を返す
+//
+// 分割して実行した場合に全体を1つのトランザクションにするには*sql.Txを渡す
+// 途中で失敗した場合はそれまでのRowsAffectedの合計とエラーを返す
+func ExecIn[T any](ctx context.Context, ex Execer, d Dialect, query string, list []T, args ...any) (int64, error) {
+	stmts, err := ExpandIn(d, query, list, args...)
+	if err != nil {
+		return 0, err
+	}
+	var total int64
+	for i, stmt := range stmts {
+		result, err := ex.ExecContext(ctx, stmt.Query, stmt.Args...)
+		if err != nil {
+			return total, fmt.Errorf("dialect: chunk %d/%d: %w", i+1, len(stmts), err)
+		}
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
@@ -0,0 +1,92 @@
+package dialect
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+// small はMaxParamsを小さくして分割を確認する
+type small struct {
+	Dialect
+}
+
+func (small) MaxParams() int {
+	return 4
+}
+
+func TestExpandIn(t *testing.T) {
+	stmts, err := ExpandIn(small{Postgres},
+		"DELETE FROM movie WHERE title <> ? AND id IN (?...) AND updated_at < ?",
+		[]int32{1, 2, 3, 4, 5}, "A", "2025-04-01",
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Statement{
+		{"DELETE FROM movie WHERE title <> $1 AND id IN ($2,$3) AND updated_at < $4", []any{"A", int32(1), int32(2), "2025-04-01"}},
+		{"DELETE FROM movie WHERE title <> $1 AND id IN ($2,$3) AND updated_at < $4", []any{"A", int32(3), int32(4), "2025-04-01"}},
+		{"DELETE FROM movie WHERE title <> $1 AND id IN ($2) AND updated_at < $3", []any{"A", int32(5), "2025-04-01"}},
+	}
+	if !slices.EqualFunc(stmts, want, func(a, b Statement) bool {
+		return a.Query == b.Query && slices.Equal(a.Args, b.Args)
+	}) {
+		t.Errorf("got  %v\nwant %v", stmts, want)
+	}
+
+	if stmts, err := ExpandIn(MySQL, "DELETE FROM movie WHERE id IN (?...)", []int{}); err != nil || len(stmts) != 0 {
+		t.Errorf("empty list: %v, %v", stmts, err)
+	}
+	if _, err := ExpandIn(MySQL, "DELETE FROM movie WHERE id IN (?)", []int{1}); err == nil {
+		t.Error("no InList: err = nil")
+	}
+}
+
+func TestExecIn(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Exec("IN (?,?,?,?)").Result(0, 4)
+	s.Exec("IN (?)").Result(0, 1)
+	db := fakedb.Open(s)
+	defer db.Close()
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	n, err := ExecIn(ctx, tx, small{MySQL}, "DELETE FROM movie WHERE id IN (?...)", []int64{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	if err != nil || n != 9 {
+		t.Errorf("ExecIn = %d, %v", n, err)
+	}
+	want := []string{
+		"Begin",
+		"Exec DELETE FROM movie WHERE id IN (?,?,?,?)",
+		"Exec DELETE FROM movie WHERE id IN (?,?,?,?)",
+		"Exec DELETE FROM movie WHERE id IN (?)",
+	}
+	if got := s.Trace(); !slices.Equal(got, want) {
+		t.Errorf("trace = %q", got)
+	}
+}
+
+func TestExecInError(t *testing.T) {
+	s := fakedb.NewScript()
+	errFK := errors.New("foreign key")
+	s.Exec("IN (?,?,?,?)").Result(0, 4).Once()
+	s.Exec("IN (?,?,?,?)").Err(errFK)
+	db := fakedb.Open(s)
+	defer db.Close()
+
+	n, err := ExecIn(context.Background(), db, small{MySQL}, "DELETE FROM movie WHERE id IN (?...)", []int64{1, 2, 3, 4, 5, 6, 7, 8, 9})
+	if !errors.Is(err, errFK) || n != 4 {
+		t.Errorf("ExecIn = %d, %v", n, err)
+	}
+	if want := "dialect: chunk 2/3: foreign key"; err.Error() != want {
+		t.Errorf("err = %q, want %q", err, want)
+	}
+}
@@ -0,0 +1,296 @@
+package dialect
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNotConsecutive はMySQLで採番された主キーが連続していると確認できなかったことを表す
+var ErrNotConsecutive = errors.New("dialect: auto_increment ids are not consecutive")
+
+// BulkInsert は採番される主キーを持つテーブルへの複数レコードのINSERT
+//
+// INSERTしたレコードはPostgreSQLではRETURNINGで取得する
+// MySQLではLastInsertIdとauto_increment_incrementから主キーを求め、同じトランザクションでSELECTする
+// 1つのINSERT文で採番される値は、innodb_autoinc_lock_modeによらず連続する（INSERT ... SELECTなどを除く）
+// 求めた主キーのレコードがINSERTした値と一致しない場合（件数が異なる場合を含む）はErrNotConsecutiveを返す
+// テーブル名とカラム名はDialect.QuoteIdentで引用符で囲む
+type BulkInsert struct {
+	Table     string
+	Key       string   // 採番される主キー
+	Columns   []string // INSERTするカラム
+	Returning []string // 取得するカラム。空の場合はKeyのみ
+	ChunkRows int      // 1つのINSERT文の最大レコード数。0の場合とMaxParamsを超える場合はMaxParamsから求める
+}
+
+// ChunkError は分割したINSERTのうち失敗したもの
+type ChunkError struct {
+	Chunk  int // 失敗したINSERT文の番号（1始まり）
+	Chunks int // INSERT文の数
+	Offset int // 失敗したINSERT文の先頭のレコードの位置
+	Rows   int // 失敗したINSERT文のレコード数
+	Err    error
+}
+
+func (e *ChunkError) Error() string {
+	return fmt.Sprintf("dialect: chunk %d/%d (rows %d-%d): %v", e.Chunk, e.Chunks, e.Offset, e.Offset+e.Rows-1, e.Err)
+}
+
+func (e *ChunkError) Unwrap() error {
+	return e.Err
+}
+
+func (b BulkInsert) returning() []string {
+	if len(b.Returning) == 0 {
+		return []string{b.Key}
+	}
+	return b.Returning
+}
+
+func (b BulkInsert) chunkRows(d Dialect) int {
+	limit := max(1, d.MaxParams()/len(b.Columns))
+	if 0 < b.ChunkRows && b.ChunkRows < limit {
+		return b.ChunkRows
+	}
+	return limit
+}
+
+// quoteIdents はnamesをQuoteIdentで囲んでカンマ区切りにする
+func quoteIdents(d Dialect, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = d.QuoteIdent(name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// selectByKeys は主キーがInListのレコードをcolumnsの順に取得するSELECT
+func (b BulkInsert) selectByKeys(d Dialect, columns []string) string {
+	key := d.QuoteIdent(b.Key)
+	return fmt.Sprintf("SELECT %s FROM %s WHERE %s IN %s ORDER BY %s", quoteIdents(d, columns), d.QuoteIdent(b.Table), key, InList, key)
+}
+
+// Exec はrowsをINSERTし、INSERTしたレコードをReturningのカラムの順で1件ずつscanに渡す
+// レコードの数が多い場合は分割してINSERTし、失敗した場合は*ChunkErrorを返す
+// scanに渡すレコードは入力と同じ順序（PostgreSQLはRETURNINGの順序）
+func (b BulkInsert) Exec(ctx context.Context, tx *sql.Tx, d Dialect, rows [][]any, scan func(*sql.Rows) error) error {
+	if len(b.Columns) == 0 {
+		return errors.New("dialect: no columns")
+	}
+	for i, row := range rows {
+		if len(row) != len(b.Columns) {
+			return fmt.Errorf("dialect: row %d has %d values, want %d", i, len(row), len(b.Columns))
+		}
+	}
+
+	size := b.chunkRows(d)
+	chunks := (len(rows) + size - 1) / size
+	increment := int64(0) // MySQLのauto_increment_increment。最初のINSERTの後に取得する
+	i := 0
+	for chunk := range slices.Chunk(rows, size) {
+		var err error
+		if d.Supports(Returning) {
+			err = b.execReturning(ctx, tx, d, chunk, scan)
+		} else {
+			err = b.execEmulated(ctx, tx, d, chunk, &increment, scan)
+		}
+		if err != nil {
+			return &ChunkError{Chunk: i + 1, Chunks: chunks, Offset: i * size, Rows: len(chunk), Err: err}
+		}
+		i++
+	}
+	return nil
+}
+
+func (b BulkInsert) insert(d Dialect, chunk [][]any) (string, []any) {
+	row := "(" + strings.Repeat("?, ", len(b.Columns)-1) + "?)"
+	values := make([]string, len(chunk))
+	args := make([]any, 0, len(chunk)*len(b.Columns))
+	for i, r := range chunk {
+		values[i] = row
+		args = append(args, r...)
+	}
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", d.QuoteIdent(b.Table), quoteIdents(d, b.Columns), strings.Join(values, ", "))
+	return query, args
+}
+
+func (b BulkInsert) execReturning(ctx context.Context, tx *sql.Tx, d Dialect, chunk [][]any, scan func(*sql.Rows) error) error {
+	query, args := b.insert(d, chunk)
+	rows, err := tx.QueryContext(ctx, d.Rebind(query)+" RETURNING "+quoteIdents(d, b.returning()), args...)
+	if err != nil {
+		return err
+	}
+	n, err := scanAll(rows, scan)
+	if err != nil {
+		return err
+	}
+	if n != len(chunk) {
+		return fmt.Errorf("dialect: %d rows returned, want %d", n, len(chunk))
+	}
+	return nil
+}
+
+func (b BulkInsert) execEmulated(ctx context.Context, tx *sql.Tx, d Dialect, chunk [][]any, increment *int64, scan func(*sql.Rows) error) error {
+	query, args := b.insert(d, chunk)
+	result, err := tx.ExecContext(ctx, d.Rebind(query), args...)
+	if err != nil {
+		return err
+	}
+	first, err := result.LastInsertId() // 最初のレコードの主キー
+	if err != nil {
+		return err
+	}
+	if rowsAffected, err := result.RowsAffected(); err != nil {
+		return err
+	} else if rowsAffected != int64(len(chunk)) {
+		return fmt.Errorf("dialect: %d rows affected, want %d", rowsAffected, len(chunk))
+	}
+
+	if *increment == 0 {
+		if err := tx.QueryRowContext(ctx, "SELECT @@auto_increment_increment").Scan(increment); err != nil {
+			return err
+		}
+	}
+	ids := make([]int64, len(chunk))
+	for i := range ids {
+		ids[i] = first + int64(i)**increment
+	}
+	stmts, err := ExpandIn(d, b.selectByKeys(d, b.returning()), ids)
+	if err != nil {
+		return err
+	}
+	if len(stmts) != 1 {
+		return fmt.Errorf("dialect: %d ids exceed the parameter limit", len(ids))
+	}
+	if err := b.verify(ctx, tx, d, ids, chunk); err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, stmts[0].Query, stmts[0].Args...)
+	if err != nil {
+		return err
+	}
+	if err := checkColumns(rows, len(b.returning())); err != nil {
+		rows.Close()
+		return err
+	}
+	n, err := scanAll(rows, scan)
+	if err != nil {
+		return err
+	}
+	if n != len(chunk) {
+		return fmt.Errorf("%w: %d rows found, want %d", ErrNotConsecutive, n, len(chunk))
+	}
+	return nil
+}
+
+// verify は主キーがidsのレコードがchunkの値でINSERTしたものかを確認する
+// 日時はカラムの精度で丸められるので比較しない
+func (b BulkInsert) verify(ctx context.Context, tx *sql.Tx, d Dialect, ids []int64, chunk [][]any) error {
+	stmts, err := ExpandIn(d, b.selectByKeys(d, b.Columns), ids)
+	if err != nil {
+		return err
+	}
+	rows, err := tx.QueryContext(ctx, stmts[0].Query, stmts[0].Args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	if err := checkColumns(rows, len(b.Columns)); err != nil {
+		return err
+	}
+	got := make([]any, len(b.Columns))
+	dest := make([]any, len(got))
+	for i := range dest {
+		dest[i] = &got[i]
+	}
+	n := 0
+	for rows.Next() {
+		if n == len(chunk) {
+			n++ // 多すぎる
+			break
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		for i, v := range got {
+			if !sameValue(chunk[n][i], v) {
+				return fmt.Errorf("%w: %s of id %d is %v, want %v", ErrNotConsecutive, b.Columns[i], ids[n], v, chunk[n][i])
+			}
+		}
+		n++
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if n != len(chunk) {
+		return fmt.Errorf("%w: %d rows found, want %d", ErrNotConsecutive, n, len(chunk))
+	}
+	return nil
+}
+
+// checkColumns はSELECTしたカラムの数を確認する
+func checkColumns(rows *sql.Rows, want int) error {
+	cols, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	if len(cols) != want {
+		return fmt.Errorf("dialect: %d columns returned, want %d", len(cols), want)
+	}
+	return nil
+}
+
+// sameValue はINSERTした値argとSELECTした値gotが同じかどうか
+// ドライバによって文字列は[]byte、真偽値は0か1の整数で返るので、文字列にしてから比較する
+func sameValue(arg, got any) bool {
+	v, err := driver.DefaultParameterConverter.ConvertValue(arg) // driver.Valuerも変換する
+	if err != nil {
+		return true // 比較できない型は確認しない
+	}
+	switch v.(type) {
+	case time.Time:
+		return true
+	case nil:
+		return got == nil
+	case float64:
+		f, err := strconv.ParseFloat(valueString(got), 64)
+		return err == nil && f == v
+	}
+	if _, ok := got.(time.Time); ok {
+		return true // 文字列で日時を指定した場合
+	}
+	return got != nil && valueString(v) == valueString(got)
+}
+
+func valueString(v any) string {
+	switch v := v.(type) {
+	case []byte:
+		return string(v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	}
+	return fmt.Sprint(v)
+}
+
+// scanAll はrowsを全てscanに渡し、件数を返す
+func scanAll(rows *sql.Rows, scan func(*sql.Rows) error) (int, error) {
+	defer rows.Close()
+	n := 0
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, rows.Err()
+}
@@ -0,0 +1,204 @@
+package dialect
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+var movieInsert = BulkInsert{
+	Table:     "movie",
+	Key:       "id",
+	Columns:   []string{"title", "created_at"},
+	Returning: []string{"id", "title"},
+	ChunkRows: 2,
+}
+
+var movieRows = [][]any{{"A", "2025-04-01"}, {"B", "2025-04-01"}, {"C", "2025-04-01"}}
+
+var movieColumns = []string{"title", "created_at"}
+
+func bulkInsert(t *testing.T, s *fakedb.Script, d Dialect) ([]string, error) {
+	t.Helper()
+	db := fakedb.Open(s)
+	defer db.Close()
+	ctx := context.Background()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	var titles []string
+	err = movieInsert.Exec(ctx, tx, d, movieRows, func(rows *sql.Rows) error {
+		var id int64
+		var title string
+		if err := rows.Scan(&id, &title); err != nil {
+			return err
+		}
+		titles = append(titles, title)
+		return nil
+	})
+	return titles, err
+}
+
+func TestBulkInsertReturning(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("RETURNING").Rows([]string{"id", "title"}, []driver.Value{int64(1), "A"}, []driver.Value{int64(2), "B"}).Once()
+	s.Query("RETURNING").Rows([]string{"id", "title"}, []driver.Value{int64(3), "C"})
+
+	titles, err := bulkInsert(t, s, Postgres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(titles, []string{"A", "B", "C"}) {
+		t.Errorf("titles = %q", titles)
+	}
+	want := []string{
+		"Begin",
+		`Query INSERT INTO "movie" ("title", "created_at") VALUES ($1, $2), ($3, $4) RETURNING "id", "title"`,
+		`Query INSERT INTO "movie" ("title", "created_at") VALUES ($1, $2) RETURNING "id", "title"`,
+		"Rollback",
+	}
+	if got := s.Trace(); !slices.Equal(got, want) {
+		t.Errorf("trace\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestBulkInsertEmulated(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Exec("INSERT INTO `movie`").Result(11, 2).Once()
+	s.Exec("INSERT INTO `movie`").Result(21, 1)
+	s.Query("@@auto_increment_increment").Rows([]string{"@@auto_increment_increment"}, []driver.Value{int64(10)})
+	s.Query("SELECT `title`, `created_at` FROM `movie` WHERE `id` IN (?,?)").Rows(movieColumns, []driver.Value{[]byte("A"), []byte("2025-04-01")}, []driver.Value{[]byte("B"), []byte("2025-04-01")})
+	s.Query("SELECT `title`, `created_at` FROM `movie` WHERE `id` IN (?)").Rows(movieColumns, []driver.Value{[]byte("C"), []byte("2025-04-01")})
+	s.Query("SELECT `id`, `title` FROM `movie` WHERE `id` IN (?,?)").Rows([]string{"id", "title"}, []driver.Value{int64(11), "A"}, []driver.Value{int64(21), "B"})
+	s.Query("SELECT `id`, `title` FROM `movie` WHERE `id` IN (?)").Rows([]string{"id", "title"}, []driver.Value{int64(21), "C"})
+
+	titles, err := bulkInsert(t, s, MySQL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(titles, []string{"A", "B", "C"}) {
+		t.Errorf("titles = %q", titles)
+	}
+	want := []string{
+		"Begin",
+		"Exec INSERT INTO `movie` (`title`, `created_at`) VALUES (?, ?), (?, ?)",
+		"Query SELECT @@auto_increment_increment",
+		"Query SELECT `title`, `created_at` FROM `movie` WHERE `id` IN (?,?) ORDER BY `id`",
+		"Query SELECT `id`, `title` FROM `movie` WHERE `id` IN (?,?) ORDER BY `id`",
+		"Exec INSERT INTO `movie` (`title`, `created_at`) VALUES (?, ?)",
+		"Query SELECT `title`, `created_at` FROM `movie` WHERE `id` IN (?) ORDER BY `id`",
+		"Query SELECT `id`, `title` FROM `movie` WHERE `id` IN (?) ORDER BY `id`",
+		"Rollback",
+	}
+	if got := s.Trace(); !slices.Equal(got, want) {
+		t.Errorf("trace\n got: %q\nwant: %q", got, want)
+	}
+	if args := s.Calls()[5].Args; !slices.Equal(args, []any{int64(11), int64(21)}) {
+		t.Errorf("SELECT args = %v", args)
+	}
+}
+
+func TestBulkInsertChunkError(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Exec("INSERT INTO `movie`").Result(11, 2).Once()
+	s.Query("@@auto_increment_increment").Rows([]string{"@@auto_increment_increment"}, []driver.Value{int64(1)})
+	s.Query("SELECT `title`, `created_at` FROM `movie`").Rows(movieColumns, []driver.Value{"A", "2025-04-01"}) // 1件しか見つからない
+
+	_, err := bulkInsert(t, s, MySQL)
+	var chunkErr *ChunkError
+	if !errors.As(err, &chunkErr) || !errors.Is(err, ErrNotConsecutive) {
+		t.Fatalf("err = %v", err)
+	}
+	if chunkErr.Chunk != 1 || chunkErr.Chunks != 2 || chunkErr.Offset != 0 || chunkErr.Rows != 2 {
+		t.Errorf("ChunkError = %+v", chunkErr)
+	}
+}
+
+func TestBulkInsertVerify(t *testing.T) {
+	tests := []struct {
+		name    string
+		columns []string
+		rows    [][]driver.Value
+		err     error
+	}{
+		// 他のセッションのレコード
+		{"other", movieColumns, [][]driver.Value{{"A", "2025-04-01"}, {"X", "2025-04-01"}}, ErrNotConsecutive},
+		{"null", movieColumns, [][]driver.Value{{"A", "2025-04-01"}, {nil, "2025-04-01"}}, ErrNotConsecutive},
+		{"columns", []string{"title"}, [][]driver.Value{{"A"}, {"B"}}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := fakedb.NewScript()
+			s.Exec("INSERT INTO `movie`").Result(11, 2)
+			s.Query("@@auto_increment_increment").Rows([]string{"@@auto_increment_increment"}, []driver.Value{int64(1)})
+			s.Query("SELECT `title`, `created_at` FROM `movie`").Rows(tt.columns, tt.rows...)
+
+			_, err := bulkInsert(t, s, MySQL)
+			if err == nil || (tt.err != nil && !errors.Is(err, tt.err)) {
+				t.Errorf("err = %v", err)
+			}
+			for _, c := range s.Calls() {
+				if c.Query == "SELECT `id`, `title` FROM `movie` WHERE `id` IN (?,?) ORDER BY `id`" {
+					t.Error("scanned without verification")
+				}
+			}
+		})
+	}
+}
+
+func TestSameValue(t *testing.T) {
+	at := time.Date(2025, 4, 1, 10, 0, 0, 123456789, time.UTC)
+	tests := []struct {
+		arg, got any
+		want     bool
+	}{
+		{"A", []byte("A"), true},
+		{"A", "A", true},
+		{"A", []byte("B"), false},
+		{int64(1), int64(1), true},
+		{1, []byte("1"), true},
+		{1, int64(2), false},
+		{true, int64(1), true},
+		{false, int64(1), false},
+		{1.5, []byte("1.50"), true},
+		{1.5, float64(2), false},
+		{nil, nil, true},
+		{nil, []byte(""), false},
+		{"", nil, false},
+		{sql.NullString{}, nil, true},
+		{at, at.Truncate(time.Second), true}, // 日時は比較しない
+		{"2025-04-01", time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		if got := sameValue(tt.arg, tt.got); got != tt.want {
+			t.Errorf("sameValue(%#v, %#v) = %v", tt.arg, tt.got, got)
+		}
+	}
+}
+
+func TestBulkInsertChunkRows(t *testing.T) {
+	tests := []struct {
+		chunkRows int
+		want      int
+	}{
+		{0, MySQL.MaxParams() / 2},
+		{2, 2},
+		{MySQL.MaxParams(), MySQL.MaxParams() / 2}, // パラメータ数の上限を超える場合は上限に合わせる
+	}
+	for _, tt := range tests {
+		b := movieInsert
+		b.ChunkRows = tt.chunkRows
+		if got := b.chunkRows(MySQL); got != tt.want {
+			t.Errorf("ChunkRows %d: %d, want %d", tt.chunkRows, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package dialect
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Outcome はUpsertの結果
+type Outcome int
+
+const (
+	Inserted  Outcome = iota + 1
+	Updated           // 一意キーが重複したので更新した
+	Unchanged         // 一意キーが重複したが、更新する値が同じだった
+)
+
+func (o Outcome) String() string {
+	switch o {
+	case Inserted:
+		return "Inserted"
+	case Updated:
+		return "Updated"
+	case Unchanged:
+		return "Unchanged"
+	}
+	return fmt.Sprintf("Outcome(%d)", int(o))
+}
+
+// Queryer は*sql.DB、*sql.Conn、*sql.Tx
+type Queryer interface {
+	Execer
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Upsert は一意キーが重複する場合に更新するINSERT
+//
+// PostgreSQLは ON CONFLICT ... DO UPDATE で、RETURNING xmax = 0 によりINSERTかUPDATEかを判定する
+// PostgreSQLは値が同じでも更新するので、WHERE ... IS DISTINCT FROM で値が変わる場合だけ更新し、行が返らない場合をUnchangedとする
+// MySQLは ON DUPLICATE KEY UPDATE で、RowsAffectedが1はINSERT、2はUPDATE、0は値が同じで更新なし
+// MySQLの判定はclientFoundRowsを有効にしていない（デフォルト）ことが前提
+type Upsert struct {
+	Table   string
+	Key     []string // 一意キーのカラム。PostgreSQLのON CONFLICTの対象
+	Columns []string // INSERTするカラム
+	Update  []string // 一意キーが重複した場合に更新するカラム
+}
+
+func (u Upsert) query(d Dialect) (string, error) {
+	if len(u.Columns) == 0 || len(u.Update) == 0 {
+		return "", errors.New("dialect: no columns to insert or update")
+	}
+	insert := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		u.Table, strings.Join(u.Columns, ", "), strings.Repeat("?, ", len(u.Columns)-1)+"?",
+	)
+	set := make([]string, len(u.Update))
+	switch {
+	case d.Supports(OnConflict):
+		if len(u.Key) == 0 {
+			return "", errors.New("dialect: no key for ON CONFLICT")
+		}
+		current := make([]string, len(u.Update))
+		excluded := make([]string, len(u.Update))
+		for i, c := range u.Update {
+			set[i] = fmt.Sprintf("%s = EXCLUDED.%s", c, c)
+			current[i] = u.Table + "." + c
+			excluded[i] = "EXCLUDED." + c
+		}
+		return d.Rebind(fmt.Sprintf("%s ON CONFLICT (%s) DO UPDATE SET %s WHERE (%s) IS DISTINCT FROM (%s) RETURNING xmax = 0",
+			insert, strings.Join(u.Key, ", "), strings.Join(set, ", "), strings.Join(current, ", "), strings.Join(excluded, ", "),
+		)), nil
+	case d.Supports(OnDuplicateKey):
+		for i, c := range u.Update {
+			set[i] = fmt.Sprintf("%s = new.%s", c, c)
+		}
+		return d.Rebind(fmt.Sprintf("%s AS new ON DUPLICATE KEY UPDATE %s", insert, strings.Join(set, ", "))), nil
+	}
+	return "", fmt.Errorf("dialect: %s does not support upsert", d.Name())
+}
+
+// Exec はColumnsの順にvaluesをINSERTし、一意キーが重複する場合はUpdateのカラムを更新する
+// 更新する値が同じだった場合はどちらのデータベースでもUnchangedを返す
+func (u Upsert) Exec(ctx context.Context, q Queryer, d Dialect, values ...any) (Outcome, error) {
+	if len(values) != len(u.Columns) {
+		return 0, fmt.Errorf("dialect: %d values, want %d", len(values), len(u.Columns))
+	}
+	query, err := u.query(d)
+	if err != nil {
+		return 0, err
+	}
+
+	if d.Supports(OnConflict) {
+		var inserted bool
+		err := q.QueryRowContext(ctx, query, values...).Scan(&inserted)
+		if errors.Is(err, sql.ErrNoRows) {
+			return Unchanged, nil // WHEREの条件で更新しなかった
+		}
+		if err != nil {
+			return 0, err
+		}
+		if inserted {
+			return Inserted, nil
+		}
+		return Updated, nil
+	}
+
+	result, err := q.ExecContext(ctx, query, values...)
+	if err != nil {
+		return 0, err
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	switch rowsAffected {
+	case 1:
+		return Inserted, nil
+	case 2:
+		return Updated, nil
+	case 0:
+		return Unchanged, nil
+	}
+	return 0, fmt.Errorf("dialect: unexpected rows affected:%d", rowsAffected)
+}
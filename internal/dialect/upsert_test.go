@@ -0,0 +1,99 @@
+package dialect
+
+import (
+	"context"
+	"database/sql/driver"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+var shopUpsert = Upsert{
+	Table:   "shop",
+	Key:     []string{"name"},
+	Columns: []string{"name", "updated_at"},
+	Update:  []string{"updated_at"},
+}
+
+func TestUpsertQuery(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{Postgres, "INSERT INTO shop (name, updated_at) VALUES ($1, $2) ON CONFLICT (name) DO UPDATE SET updated_at = EXCLUDED.updated_at WHERE (shop.updated_at) IS DISTINCT FROM (EXCLUDED.updated_at) RETURNING xmax = 0"},
+		{MySQL, "INSERT INTO shop (name, updated_at) VALUES (?, ?) AS new ON DUPLICATE KEY UPDATE updated_at = new.updated_at"},
+	}
+	for _, tt := range tests {
+		got, err := shopUpsert.query(tt.dialect)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != tt.want {
+			t.Errorf("%s\n got: %s\nwant: %s", tt.dialect.Name(), got, tt.want)
+		}
+	}
+}
+
+func upsert(t *testing.T, s *fakedb.Script, d Dialect, n int) ([]Outcome, error) {
+	t.Helper()
+	db := fakedb.Open(s)
+	defer db.Close()
+	var outcomes []Outcome
+	for range n {
+		o, err := shopUpsert.Exec(context.Background(), db, d, "shop1st", "2025-04-01")
+		if err != nil {
+			return outcomes, err
+		}
+		outcomes = append(outcomes, o)
+	}
+	return outcomes, nil
+}
+
+func TestUpsertPostgres(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("ON CONFLICT").Rows([]string{"?column?"}, []driver.Value{true}).Once()
+	s.Query("ON CONFLICT").Rows([]string{"?column?"}, []driver.Value{false}).Once()
+	s.Query("ON CONFLICT").Rows([]string{"?column?"}) // 値が同じでWHEREの条件に合わない場合は行が返らない
+
+	got, err := upsert(t, s, Postgres, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Outcome{Inserted, Updated, Unchanged}; !slices.Equal(got, want) {
+		t.Errorf("outcomes = %v, want %v", got, want)
+	}
+}
+
+func TestUpsertMySQL(t *testing.T) {
+	s := fakedb.NewScript()
+	// 更新した場合のRowsAffectedは2、値が同じで更新しなかった場合は0
+	s.Exec("ON DUPLICATE KEY").Result(1, 1).Once()
+	s.Exec("ON DUPLICATE KEY").Result(1, 2).Once()
+	s.Exec("ON DUPLICATE KEY").Result(1, 0).Once()
+	s.Exec("ON DUPLICATE KEY").Result(1, 3)
+
+	got, err := upsert(t, s, MySQL, 4)
+	if want := []Outcome{Inserted, Updated, Unchanged}; !slices.Equal(got, want) {
+		t.Errorf("outcomes = %v, want %v", got, want)
+	}
+	if err == nil || !strings.Contains(err.Error(), "unexpected rows affected:3") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestUpsertInvalid(t *testing.T) {
+	db := fakedb.Open(fakedb.NewScript())
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := shopUpsert.Exec(ctx, db, MySQL, "shop1st"); err == nil {
+		t.Error("values: want error")
+	}
+	noKey := shopUpsert
+	noKey.Key = nil
+	if _, err := noKey.Exec(ctx, db, Postgres, "shop1st", "2025-04-01"); err == nil {
+		t.Error("key: want error")
+	}
+}
@@ -0,0 +1,183 @@
+package fakedb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+)
+
+type conn struct {
+	script *Script
+	id     int64
+	closed bool
+}
+
+var (
+	_ driver.Conn               = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+)
+
+func (c *conn) call(op Op, query string, args []driver.NamedValue) *Rule {
+	values := make([]any, len(args))
+	for i, a := range args {
+		values[i] = a.Value
+	}
+	return c.script.call(Call{Op: op, ConnID: c.id, Query: query, Args: values})
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	if r := c.call(OpPrepare, query, nil); r != nil && r.err != nil {
+		return nil, r.err
+	}
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	c.call(OpClose, "", nil)
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	r := c.script.call(Call{Op: OpBegin, ConnID: c.id, Isolation: sql.IsolationLevel(opts.Isolation)})
+	if r != nil && r.err != nil {
+		return nil, r.err
+	}
+	return &tx{conn: c}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r := c.call(OpExec, query, args)
+	if r == nil {
+		return Result{}, nil
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	if r.result == nil {
+		return Result{}, nil
+	}
+	return r.result, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	r := c.call(OpQuery, query, args)
+	if r == nil {
+		return nil, fmt.Errorf("%w: %s", ErrNoRule, query)
+	}
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &rows{columns: r.columns, rows: r.rows}, nil
+}
+
+// CheckNamedValue は任意の値をそのまま受け付ける
+func (c *conn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+var (
+	_ driver.StmtExecContext  = (*stmt)(nil)
+	_ driver.StmtQueryContext = (*stmt)(nil)
+)
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, v := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: v}
+	}
+	return named
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+type tx struct {
+	conn *conn
+}
+
+func (t *tx) Commit() error {
+	if r := t.conn.call(OpCommit, "", nil); r != nil && r.err != nil {
+		return r.err
+	}
+	return nil
+}
+
+func (t *tx) Rollback() error {
+	if r := t.conn.call(OpRollback, "", nil); r != nil && r.err != nil {
+		return r.err
+	}
+	return nil
+}
+
+type rows struct {
+	columns []string
+	rows    [][]driver.Value
+	pos     int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
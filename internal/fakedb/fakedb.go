@@ -0,0 +1,120 @@
+// Package fakedb はテスト用のdatabase/sqlドライバ
+//
+// 実際のデータベースには接続せず、呼び出しを記録してScriptに従った結果を返す
+package fakedb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+)
+
+// DriverName はsql.Registerで登録する名前
+const DriverName = "fakedb"
+
+func init() {
+	sql.Register(DriverName, &Driver{})
+}
+
+var (
+	scriptsMu sync.Mutex
+	scripts   = map[string]*Script{}
+	nextName  atomic.Int64
+)
+
+func register(s *Script) string {
+	name := fmt.Sprintf("script%d", nextName.Add(1))
+	scriptsMu.Lock()
+	scripts[name] = s
+	scriptsMu.Unlock()
+	return name
+}
+
+// Open はScriptに従うデータベースを開く
+func Open(s *Script) *sql.DB {
+	db, err := sql.Open(DriverName, register(s))
+	if err != nil {
+		panic(err) // Driver.OpenConnectorは名前を確認しないので発生しない
+	}
+	return db
+}
+
+// NewConnector はScriptに従うConnectorを返す
+func NewConnector(s *Script) driver.Connector {
+	return &connector{name: register(s), driver: &Driver{}}
+}
+
+// Driver はsql.Registerで登録するドライバ。データソース名はOpenで割り当てたScriptの名前
+type Driver struct{}
+
+var _ driver.DriverContext = (*Driver)(nil)
+
+func (d *Driver) Open(name string) (driver.Conn, error) {
+	c, err := d.OpenConnector(name)
+	if err != nil {
+		return nil, err
+	}
+	return c.Connect(context.Background())
+}
+
+func (d *Driver) OpenConnector(name string) (driver.Connector, error) {
+	return &connector{name: name, driver: d}, nil
+}
+
+type connector struct {
+	name   string
+	driver *Driver
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	scriptsMu.Lock()
+	s, ok := scripts[c.name]
+	scriptsMu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("fakedb: unknown script:%s", c.name)
+	}
+	return s.connect(ctx)
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.driver
+}
+
+// Op は記録する呼び出しの種類
+type Op string
+
+const (
+	OpConnect  Op = "Connect"
+	OpPrepare  Op = "Prepare"
+	OpExec     Op = "Exec"
+	OpQuery    Op = "Query"
+	OpBegin    Op = "Begin"
+	OpCommit   Op = "Commit"
+	OpRollback Op = "Rollback"
+	OpClose    Op = "Close"
+)
+
+// Call は記録された呼び出し
+type Call struct {
+	Op        Op
+	ConnID    int64
+	Query     string
+	Args      []any
+	Isolation sql.IsolationLevel // OpBeginのみ
+}
+
+// String は "Exec INSERT INTO ..." の形式。SQLの空白は1つにまとめる
+func (c Call) String() string {
+	query := strings.Join(strings.Fields(c.Query), " ")
+	switch {
+	case c.Op == OpBegin && c.Isolation != sql.LevelDefault:
+		return fmt.Sprintf("%s %s", c.Op, c.Isolation)
+	case query == "":
+		return string(c.Op)
+	}
+	return fmt.Sprintf("%s %s", c.Op, query)
+}
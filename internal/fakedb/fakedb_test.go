@@ -0,0 +1,73 @@
+package fakedb
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestScript(t *testing.T) {
+	s := NewScript()
+	errDup := errors.New("duplicate")
+	s.Exec("INSERT").Err(errDup).Once()
+	s.Exec("INSERT").Result(7, 1)
+	s.Query("SELECT").Rows([]string{"id", "name"}, []driver.Value{int64(1), "a"}, []driver.Value{int64(2), "b"})
+
+	db := Open(s)
+	defer db.Close()
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "INSERT INTO t VALUES (?)", 1); !errors.Is(err, errDup) {
+		t.Fatalf("first exec: err = %v", err)
+	}
+	res, err := db.ExecContext(ctx, "INSERT INTO t VALUES (?)", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id, _ := res.LastInsertId(); id != 7 {
+		t.Errorf("LastInsertId = %d", id)
+	}
+
+	rows, err := db.QueryContext(ctx, "SELECT id, name FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for rows.Next() {
+		var id int64
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	rows.Close()
+	if !slices.Equal(names, []string{"a", "b"}) {
+		t.Errorf("names = %q", names)
+	}
+
+	if _, err := db.QueryContext(ctx, "UPDATE t SET name = 'c' RETURNING id"); !errors.Is(err, ErrNoRule) {
+		t.Errorf("query without rule: err = %v", err)
+	}
+
+	tx, err := db.BeginTx(ctx, &sql.TxOptions{Isolation: sql.LevelSerializable})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+
+	want := []string{
+		"Exec INSERT INTO t VALUES (?)",
+		"Exec INSERT INTO t VALUES (?)",
+		"Query SELECT id, name FROM t",
+		"Query UPDATE t SET name = 'c' RETURNING id",
+		"Begin Serializable",
+		"Rollback",
+	}
+	if got := s.Trace(); !slices.Equal(got, want) {
+		t.Errorf("trace\n got: %q\nwant: %q", got, want)
+	}
+}
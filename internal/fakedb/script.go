@@ -0,0 +1,191 @@
+package fakedb
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"sync"
+)
+
+// Rule はScriptに登録する応答
+type Rule struct {
+	op      Op
+	pattern string
+	times   int // 0は無制限
+	used    int
+
+	columns []string
+	rows    [][]driver.Value
+	result  driver.Result
+	err     error
+}
+
+// Rows はQueryの応答としてレコードを返す
+func (r *Rule) Rows(columns []string, rows ...[]driver.Value) *Rule {
+	r.columns = columns
+	r.rows = rows
+	return r
+}
+
+// Result はExecの応答としてLastInsertIdとRowsAffectedを返す
+func (r *Rule) Result(lastInsertId, rowsAffected int64) *Rule {
+	r.result = Result{LastID: lastInsertId, Affected: rowsAffected}
+	return r
+}
+
+// DriverResult はExecの応答として任意のdriver.Resultを返す
+func (r *Rule) DriverResult(result driver.Result) *Rule {
+	r.result = result
+	return r
+}
+
+// Err は応答としてエラーを返す
+func (r *Rule) Err(err error) *Rule {
+	r.err = err
+	return r
+}
+
+// Times は応答する回数を制限する
+func (r *Rule) Times(n int) *Rule {
+	r.times = n
+	return r
+}
+
+// Once はTimes(1)と同じ
+func (r *Rule) Once() *Rule {
+	return r.Times(1)
+}
+
+// Result はdriver.Resultの実装
+type Result struct {
+	LastID   int64
+	Affected int64
+}
+
+func (r Result) LastInsertId() (int64, error) {
+	return r.LastID, nil
+}
+
+func (r Result) RowsAffected() (int64, error) {
+	return r.Affected, nil
+}
+
+// ErrNoRule はQueryに対応するRuleがないことを表す
+var ErrNoRule = errors.New("fakedb: no rule")
+
+// Script は呼び出しの記録と応答の定義
+//
+// 応答は登録順に探し、操作が一致しSQLにパターンを含む最初のRuleを使う
+// ExecにRuleがない場合はLastInsertIdとRowsAffectedが0の結果を返し
+// QueryにRuleがない場合はErrNoRuleを返す
+type Script struct {
+	mu     sync.Mutex
+	rules  []*Rule
+	calls  []Call
+	nextID int64
+}
+
+func NewScript() *Script {
+	return &Script{}
+}
+
+func (s *Script) on(op Op, pattern string) *Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r := &Rule{op: op, pattern: pattern}
+	s.rules = append(s.rules, r)
+	return r
+}
+
+// Exec はSQLにpatternを含むExecへの応答を登録する
+func (s *Script) Exec(pattern string) *Rule {
+	return s.on(OpExec, pattern)
+}
+
+// Query はSQLにpatternを含むQueryへの応答を登録する
+func (s *Script) Query(pattern string) *Rule {
+	return s.on(OpQuery, pattern)
+}
+
+// Prepare はSQLにpatternを含むPrepareへの応答を登録する（エラーのみ有効）
+func (s *Script) Prepare(pattern string) *Rule {
+	return s.on(OpPrepare, pattern)
+}
+
+// Begin はBeginへの応答を登録する（エラーのみ有効）
+func (s *Script) Begin() *Rule {
+	return s.on(OpBegin, "")
+}
+
+// Commit はCommitへの応答を登録する（エラーのみ有効）
+func (s *Script) Commit() *Rule {
+	return s.on(OpCommit, "")
+}
+
+// Rollback はRollbackへの応答を登録する（エラーのみ有効）
+func (s *Script) Rollback() *Rule {
+	return s.on(OpRollback, "")
+}
+
+// Connect は接続への応答を登録する（エラーのみ有効）
+func (s *Script) Connect() *Rule {
+	return s.on(OpConnect, "")
+}
+
+// Calls は記録された呼び出し
+func (s *Script) Calls() []Call {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Call(nil), s.calls...)
+}
+
+// Trace は記録された呼び出しをCall.Stringで文字列にしたもの
+// Connectと物理的なCloseは含めない
+func (s *Script) Trace() []string {
+	var trace []string
+	for _, c := range s.Calls() {
+		if c.Op == OpConnect || c.Op == OpClose {
+			continue
+		}
+		trace = append(trace, c.String())
+	}
+	return trace
+}
+
+// Reset は記録された呼び出しを消去する
+func (s *Script) Reset() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = nil
+}
+
+// call は呼び出しを記録して対応するRuleを返す
+func (s *Script) call(c Call) *Rule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, c)
+	for _, r := range s.rules {
+		if r.op != c.Op || !strings.Contains(c.Query, r.pattern) {
+			continue
+		}
+		if r.times != 0 && r.used >= r.times {
+			continue
+		}
+		r.used++
+		return r
+	}
+	return nil
+}
+
+func (s *Script) connect(context.Context) (driver.Conn, error) {
+	s.mu.Lock()
+	s.nextID++
+	id := s.nextID
+	s.mu.Unlock()
+
+	if r := s.call(Call{Op: OpConnect, ConnID: id}); r != nil && r.err != nil {
+		return nil, r.err
+	}
+	return &conn{script: s, id: id}, nil
+}
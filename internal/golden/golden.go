@@ -0,0 +1,230 @@
+// Package golden はslogのJSON出力をtestdata/*.goldenと比較するテスト用の仕組み
+//
+// 実行毎に変わる値は正規化してから比較する
+//
+//   - ログの時刻は出力しない
+//   - time.Timeの値は "<time>"、time.Durationの値は "<duration>"
+//   - IDKeysのキーの値（0以外の整数とそのスライス）は出現順に "<id1>" "<id2>" ...
+//
+// go test -update でgoldenファイルを更新する
+// go test -live -run TestLive で、登録されたサンプルを実際のデータベースで実行して同じgoldenファイルと比較する
+package golden
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+var (
+	update = flag.Bool("update", false, "testdata/*.goldenを更新する")
+	live   = flag.Bool("live", false, "サンプルをdocker-compose.ymlのデータベースで実行してtestdata/*.goldenと比較する")
+)
+
+// IDKeys は採番された値を記録するキー
+var IDKeys = []string{"id", "ids", "insertId", "lastInsertId"}
+
+// Log はCaptureで記録したslogの出力
+type Log struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+	ids map[int64]string
+}
+
+func (l *Log) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.buf.Write(p)
+}
+
+// Bytes は記録した出力
+func (l *Log) Bytes() []byte {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return bytes.Clone(l.buf.Bytes())
+}
+
+// Capture はテストの終了までslogのデフォルトを正規化したJSON出力の記録に差し替える
+// slogのデフォルトを差し替えるので、t.Parallelとは併用できない
+func Capture(t testing.TB) *Log {
+	t.Helper()
+	l := &Log{ids: map[int64]string{}}
+	prev := slog.Default()
+	slog.SetDefault(slog.New(slog.NewJSONHandler(l, &slog.HandlerOptions{ReplaceAttr: l.replace})))
+	t.Cleanup(func() { slog.SetDefault(prev) })
+	return l
+}
+
+// Assert は記録した出力をgoldenファイルと比較する
+func (l *Log) Assert(t testing.TB) {
+	t.Helper()
+	Assert(t, l.Bytes())
+}
+
+func (l *Log) replace(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) == 0 && a.Key == slog.TimeKey {
+		return slog.Attr{}
+	}
+	switch a.Value.Kind() {
+	case slog.KindTime:
+		return slog.String(a.Key, "<time>")
+	case slog.KindDuration:
+		return slog.String(a.Key, "<duration>")
+	}
+	for _, k := range IDKeys {
+		if a.Key == k {
+			return l.replaceID(a)
+		}
+	}
+	return a
+}
+
+func (l *Log) replaceID(a slog.Attr) slog.Attr {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	v := reflect.ValueOf(a.Value.Any())
+	if v.Kind() != reflect.Slice {
+		if s, ok := l.id(v); ok && s != "0" {
+			return slog.String(a.Key, s)
+		}
+		return a
+	}
+	ids := make([]string, v.Len())
+	for i := range ids {
+		s, ok := l.id(v.Index(i))
+		if !ok {
+			return a
+		}
+		ids[i] = s
+	}
+	return slog.Any(a.Key, ids)
+}
+
+func (l *Log) id(v reflect.Value) (string, bool) {
+	if v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	var n int64
+	switch {
+	case v.CanInt():
+		n = v.Int()
+	case v.CanUint():
+		n = int64(v.Uint())
+	default:
+		return "", false
+	}
+	if n == 0 {
+		return "0", true
+	}
+	s, ok := l.ids[n]
+	if !ok {
+		s = fmt.Sprintf("<id%d>", len(l.ids)+1)
+		l.ids[n] = s
+	}
+	return s, true
+}
+
+// Path はテストに対応するgoldenファイルのパス
+func Path(t testing.TB) string {
+	return filepath.Join("testdata", strings.ReplaceAll(t.Name(), "/", "_")+".golden")
+}
+
+// Assert はgotをgoldenファイルと比較する。-updateの場合はgoldenファイルを書き換える
+func Assert(t testing.TB, got []byte) {
+	t.Helper()
+	assertFile(t, Path(t), got)
+}
+
+func assertFile(t testing.TB, path string, got []byte) {
+	t.Helper()
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (go test -update で作成)", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s と一致しない (go test -update で更新)\n--- got\n%s--- want\n%s", path, got, want)
+	}
+}
+
+// liveCase はLiveで実行するサンプルとドライバ。nameはfakedbのテストと同じ名前
+type liveCase struct {
+	name   string
+	driver string
+}
+
+// liveCases はサンプルに対応するfakedbのテストの名前。Eitherはサブテストにdialectの名前を付ける
+func liveCases(e runner.Example) []liveCase {
+	if e.Target != runner.Either {
+		return []liveCase{{"Test" + e.Name, ""}}
+	}
+	return []liveCase{
+		{"Test" + e.Name + "_postgres", dbsetup.DriverPgx},
+		{"Test" + e.Name + "_mysql", dbsetup.DriverMySQL},
+	}
+}
+
+// Live は登録されたサンプルをsetupのデータベースで実行し、fakedbのテストと同じgoldenファイルと比較する
+// fakedbのスクリプトと実際のドライバの動作の違い（ドライバの更新による変化など）を検出するためのもの
+// -liveが指定されていない場合と、データベースに接続できない場合はスキップする
+// goldenファイルがないサンプル（fakedbで障害を再現するものなど）は対象外
+func Live(t *testing.T, setup runner.Setup) {
+	if !*live {
+		t.Skip("go test -live で実行する")
+	}
+	for _, e := range runner.Examples() {
+		for _, c := range liveCases(e) {
+			t.Run(c.name, func(t *testing.T) {
+				path := filepath.Join("testdata", c.name+".golden")
+				if _, err := os.Stat(path); err != nil {
+					t.Skipf("no golden file: %v", err)
+				}
+				runLive(t, setup, e, c.driver, path)
+			})
+		}
+	}
+}
+
+func runLive(t *testing.T, setup runner.Setup, e runner.Example, driverName, path string) {
+	t.Helper()
+	driverName, err := e.Driver(driverName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), runner.DefaultTimeout)
+	defer cancel()
+
+	// セットアップにはサンプルと同じctxを渡す（ex02はctxのキャンセルで新しい接続を拒否する）
+	dbs, err := setup.Open(ctx, e, driverName)
+	if err != nil {
+		t.Skipf("%s: database is not available: %v", driverName, err)
+	}
+	defer dbs.Close(ctx)
+
+	// セットアップのログは比較しない
+	out := Capture(t)
+	if err := e.Run(ctx, dbs); err != nil && (e.ExpectErr == nil || !errors.Is(err, e.ExpectErr)) {
+		t.Fatal(err)
+	}
+	assertFile(t, path, out.Bytes())
+}
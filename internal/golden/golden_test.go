@@ -0,0 +1,43 @@
+package golden
+
+import (
+	"log/slog"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/runner"
+)
+
+func TestCapture(t *testing.T) {
+	out := Capture(t)
+	slog.Info("INSERT", "ids", []any{int32(7), int32(8)}, "elapsed", 3*time.Millisecond)
+	slog.Info("SELECT", "id", int64(8), "created_at", time.Now(), "lastInsertId", 0)
+
+	const want = `{"level":"INFO","msg":"INSERT","ids":["<id1>","<id2>"],"elapsed":"<duration>"}
+{"level":"INFO","msg":"SELECT","id":"<id2>","created_at":"<time>","lastInsertId":0}
+`
+	if got := string(out.Bytes()); got != want {
+		t.Errorf("got:\n%swant:\n%s", got, want)
+	}
+}
+
+func TestLiveCases(t *testing.T) {
+	tests := []struct {
+		e    runner.Example
+		want []liveCase
+	}{
+		{runner.Example{Name: "Ex01Pg01", Target: runner.Pg}, []liveCase{{"TestEx01Pg01", ""}}},
+		{runner.Example{Name: "Ex04Tx01", Target: runner.Both}, []liveCase{{"TestEx04Tx01", ""}}},
+		{runner.Example{Name: "Ex01Dialect01", Target: runner.Either}, []liveCase{
+			{"TestEx01Dialect01_postgres", dbsetup.DriverPgx},
+			{"TestEx01Dialect01_mysql", dbsetup.DriverMySQL},
+		}},
+	}
+	for _, tt := range tests {
+		if got := liveCases(tt.e); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: %v", tt.e.Name, got)
+		}
+	}
+}
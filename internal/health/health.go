@@ -0,0 +1,157 @@
+// Package health はPingContextによる定期的なヘルスチェック
+//
+// 状態が変わった時点でslogに出力し、最新の状態はStatusで確認できる
+package health
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultTimeout はPing1回あたりのタイムアウトのデフォルト
+const DefaultTimeout = time.Second
+
+// Status はヘルスチェックの結果
+type Status struct {
+	Healthy   bool
+	Err       error // 最後に失敗したPingのエラー。成功した場合はnil
+	CheckedAt time.Time
+	Latency   time.Duration
+	Failures  int // 連続して失敗した回数
+}
+
+// Checker は定期的にPingContextを実行する
+type Checker struct {
+	interval time.Duration
+	Timeout  time.Duration // Ping1回あたりのタイムアウト。0の場合はDefaultTimeout
+
+	mu     sync.Mutex
+	db     *sql.DB
+	status Status
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// New はintervalの間隔でチェックするCheckerを返す
+func New(interval time.Duration) *Checker {
+	return &Checker{interval: interval}
+}
+
+// Wrap はConnectorをラップする。DB.Closeの時点でStopする
+func (c *Checker) Wrap(conn driver.Connector) driver.Connector {
+	return &connector{Connector: conn, checker: c}
+}
+
+type connector struct {
+	driver.Connector
+	checker *Checker
+}
+
+func (c *connector) Close() error {
+	c.checker.Stop()
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Start はdbのチェックを開始する。最初のチェックはintervalの後
+func (c *Checker) Start(db *sql.DB) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.db != nil {
+		return
+	}
+	c.db = db
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	c.done = make(chan struct{})
+	go c.run(ctx, c.done)
+}
+
+func (c *Checker) run(ctx context.Context, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			c.Check(ctx)
+		}
+	}
+}
+
+// Stop はチェックを止める。実行中のPingはキャンセルする
+func (c *Checker) Stop() {
+	c.mu.Lock()
+	cancel, done := c.cancel, c.done
+	c.cancel = nil
+	c.mu.Unlock()
+	if cancel == nil {
+		return
+	}
+	cancel()
+	<-done
+}
+
+// Check はPingContextを1回実行して状態を更新する。状態が変わった場合はslogに出力する
+// Startの前はチェックせずにゼロ値を返す
+func (c *Checker) Check(ctx context.Context) Status {
+	c.mu.Lock()
+	db := c.db
+	c.mu.Unlock()
+	if db == nil {
+		return Status{}
+	}
+
+	timeout := c.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	pingCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	start := time.Now()
+	err := db.PingContext(pingCtx)
+	if err != nil && (ctx.Err() != nil || closed(err)) {
+		// Stopによるキャンセルと、DB.Closeの途中のPingは結果に含めない
+		return c.Status()
+	}
+
+	c.mu.Lock()
+	prev := c.status
+	s := Status{Healthy: err == nil, Err: err, CheckedAt: start, Latency: time.Since(start)}
+	if err != nil {
+		s.Failures = prev.Failures + 1
+	}
+	c.status = s
+	c.mu.Unlock()
+
+	switch {
+	case err != nil && s.Failures == 1:
+		slog.WarnContext(ctx, "health", "healthy", false, "err", err)
+	case err == nil && (prev.Failures != 0 || prev.CheckedAt.IsZero()):
+		slog.InfoContext(ctx, "health", "healthy", true, "latency", s.Latency)
+	}
+	return s
+}
+
+// closed はDB.Closeの後のPingのエラーかどうか
+// database/sqlの "sql: database is closed" はエクスポートされていないのでメッセージで判定する
+func closed(err error) bool {
+	return errors.Is(err, sql.ErrConnDone) || err.Error() == "sql: database is closed"
+}
+
+// Status は最新の状態。まだチェックしていない場合はゼロ値（Healthyはfalse）
+func (c *Checker) Status() Status {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.status
+}
@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+func TestCheck(t *testing.T) {
+	errDown := errors.New("down")
+	s := fakedb.NewScript()
+	s.Connect().Err(errDown).Times(2)
+	db := fakedb.Open(s)
+	defer db.Close()
+	db.SetMaxIdleConns(-1) // Pingの度に接続する
+	ctx := context.Background()
+
+	c := New(time.Hour)
+	if got := c.Check(ctx); got != (Status{}) {
+		t.Errorf("before Start = %+v", got)
+	}
+	c.Start(db)
+	defer c.Stop()
+
+	for i := 1; i <= 2; i++ {
+		got := c.Check(ctx)
+		if got.Healthy || !errors.Is(got.Err, errDown) || got.Failures != i {
+			t.Errorf("check %d = %+v", i, got)
+		}
+	}
+	got := c.Check(ctx)
+	if !got.Healthy || got.Err != nil || got.Failures != 0 || got.CheckedAt.IsZero() {
+		t.Errorf("recovered = %+v", got)
+	}
+	if c.Status() != got {
+		t.Errorf("Status = %+v", c.Status())
+	}
+}
+
+func TestWrap(t *testing.T) {
+	s := fakedb.NewScript()
+	c := New(5 * time.Millisecond)
+	db := sql.OpenDB(c.Wrap(fakedb.NewConnector(s)))
+	c.Start(db)
+
+	deadline := time.Now().Add(time.Second)
+	for !c.Status().Healthy {
+		if time.Now().After(deadline) {
+			t.Fatal("no check")
+		}
+		time.Sleep(time.Millisecond)
+	}
+
+	// DB.Closeの時点でチェックは止まる
+	if err := db.Close(); err != nil {
+		t.Fatal(err)
+	}
+	checked := c.Status().CheckedAt
+	time.Sleep(20 * time.Millisecond)
+	if got := c.Status().CheckedAt; !got.Equal(checked) {
+		t.Errorf("checked after Close: %v, %v", checked, got)
+	}
+}
+
+func TestCheckClosed(t *testing.T) {
+	db := fakedb.Open(fakedb.NewScript())
+	c := New(time.Hour)
+	c.Start(db)
+	defer c.Stop()
+	db.Close()
+
+	// DB.Closeの途中や後のチェックはunhealthyにしない
+	if got := c.Check(context.Background()); got != (Status{}) {
+		t.Errorf("after Close = %+v", got)
+	}
+}
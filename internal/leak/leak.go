@@ -0,0 +1,230 @@
+// Package leak はクローズされていない*sql.Conn、*sql.Tx、*sql.Rowsを検出する
+//
+// sqltraceでドライバのConnectorをラップし、プールからの取り出し、トランザクション開始、クエリ実行の時点の
+// スタックトレースを記録する。返却、コミットまたはロールバック、Rowsのクローズで記録を消す
+// DB.Closeの時点で残っているものをslogで報告する。デバッグ用でスタックトレースの取得に時間がかかる
+package leak
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/sqltrace"
+)
+
+// Kind は検出する対象
+type Kind string
+
+const (
+	Conn Kind = "conn" // プールから取り出したコネクション（*sql.Connや実行中の*sql.DBの呼び出し）
+	Tx   Kind = "tx"
+	Rows Kind = "rows"
+)
+
+// Leak はクローズされていないもの1つ分
+type Leak struct {
+	Kind   Kind
+	ConnID int64  // sqltraceの物理コネクションの番号
+	Query  string // Rowsのみ
+	Since  time.Time
+	Age    time.Duration
+	Frames []runtime.Frame // 取得した時点のスタックトレース（database/sqlなどのフレームは除く）
+}
+
+// At は先頭のフレームを "パッケージ名.関数名 (ファイル名:行番号)" の形式にしたもの
+func (l Leak) At() string {
+	if len(l.Frames) == 0 {
+		return ""
+	}
+	f := l.Frames[0]
+	return fmt.Sprintf("%s (%s:%d)", f.Function[strings.LastIndex(f.Function, "/")+1:], f.File[strings.LastIndex(f.File, "/")+1:], f.Line)
+}
+
+type entry struct {
+	kind   Kind
+	connID int64
+	query  string
+	since  time.Time
+	pcs    []uintptr
+}
+
+// Detector はクローズされていないものを記録する
+type Detector struct {
+	threshold time.Duration
+	now       func() time.Time
+
+	mu   sync.Mutex
+	open []*entry
+}
+
+// New はthreshold以上クローズされていないものを報告するDetectorを返す
+func New(threshold time.Duration) *Detector {
+	return &Detector{threshold: threshold, now: time.Now}
+}
+
+// Wrap はConnectorをラップする。DB.Closeの時点で残っているものを報告する
+func (d *Detector) Wrap(c driver.Connector) driver.Connector {
+	return &connector{Connector: sqltrace.Wrap(c, d.Observe), detector: d}
+}
+
+type connector struct {
+	driver.Connector
+	detector *Detector
+}
+
+func (c *connector) Close() error {
+	c.detector.Log(context.Background())
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Observe はsqltraceのObserver。他のObserverと組み合わせる場合に直接使う
+func (d *Detector) Observe(ev sqltrace.Event) {
+	switch ev.Op {
+	case sqltrace.OpConnect, sqltrace.OpResetSession:
+		if ev.Err == nil {
+			d.add(Conn, ev.ConnID, "")
+		}
+	case sqltrace.OpCheckin:
+		d.remove(Conn, ev.ConnID, "")
+	case sqltrace.OpClose:
+		d.removeConn(ev.ConnID)
+	case sqltrace.OpBegin:
+		if ev.Err == nil {
+			d.add(Tx, ev.ConnID, "")
+		}
+	case sqltrace.OpCommit, sqltrace.OpRollback:
+		d.remove(Tx, ev.ConnID, "")
+	case sqltrace.OpQuery:
+		if ev.Err == nil {
+			d.add(Rows, ev.ConnID, ev.Query)
+		}
+	case sqltrace.OpRowsClose:
+		d.remove(Rows, ev.ConnID, ev.Query)
+	}
+}
+
+func (d *Detector) add(kind Kind, connID int64, query string) {
+	pcs := make([]uintptr, 64)
+	pcs = pcs[:runtime.Callers(3, pcs)]
+	e := &entry{kind: kind, connID: connID, query: query, since: d.now(), pcs: pcs}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.open = append(d.open, e)
+}
+
+// remove は条件に合う最も新しいものを消す
+func (d *Detector) remove(kind Kind, connID int64, query string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for i := len(d.open) - 1; 0 <= i; i-- {
+		e := d.open[i]
+		if e.kind == kind && e.connID == connID && e.query == query {
+			d.open = slices.Delete(d.open, i, i+1)
+			return
+		}
+	}
+}
+
+func (d *Detector) removeConn(connID int64) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.open = slices.DeleteFunc(d.open, func(e *entry) bool {
+		return e.connID == connID
+	})
+}
+
+// Leaks はthreshold以上クローズされていないものを古い順に返す
+func (d *Detector) Leaks() []Leak {
+	now := d.now()
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	var leaks []Leak
+	for _, e := range d.open {
+		age := now.Sub(e.since)
+		if age < d.threshold {
+			continue
+		}
+		leaks = append(leaks, Leak{
+			Kind:   e.kind,
+			ConnID: e.connID,
+			Query:  e.query,
+			Since:  e.since,
+			Age:    age,
+			Frames: frames(e.pcs),
+		})
+	}
+	return leaks
+}
+
+// skipped はスタックトレースから除くパッケージ
+var skipped = []string{
+	"runtime.",
+	"database/sql.",
+	"github.com/ystkg/db-examples/internal/sqltrace.",
+	"github.com/ystkg/db-examples/internal/leak.(*",
+}
+
+func frames(pcs []uintptr) []runtime.Frame {
+	var list []runtime.Frame
+	it := runtime.CallersFrames(pcs)
+	for {
+		f, more := it.Next()
+		if !slices.ContainsFunc(skipped, func(prefix string) bool {
+			return strings.HasPrefix(f.Function, prefix)
+		}) {
+			list = append(list, f)
+		}
+		if !more {
+			return list
+		}
+	}
+}
+
+// Log はLeaksをslogで報告する。スタックトレースは先頭のフレームだけ出力する
+func (d *Detector) Log(ctx context.Context) {
+	for _, l := range d.Leaks() {
+		args := []any{"kind", string(l.Kind), "conn", l.ConnID, "age", l.Age, "at", l.At()}
+		if l.Query != "" {
+			args = append(args, "query", l.Query)
+		}
+		slog.WarnContext(ctx, "leak", args...)
+	}
+}
+
+// Report はLeaksをスタックトレース付きでwに出力し、件数を返す
+func (d *Detector) Report(w io.Writer) (int, error) {
+	leaks := d.Leaks()
+	if err := Write(w, leaks); err != nil {
+		return 0, err
+	}
+	return len(leaks), nil
+}
+
+// Write はleaksをスタックトレース付きでwに出力する
+func Write(w io.Writer, leaks []Leak) error {
+	var b strings.Builder
+	for _, l := range leaks {
+		fmt.Fprintf(&b, "%s conn=%d age=%s", l.Kind, l.ConnID, l.Age.Round(time.Millisecond))
+		if l.Query != "" {
+			fmt.Fprintf(&b, " query=%q", l.Query)
+		}
+		b.WriteString("\n")
+		for _, f := range l.Frames {
+			fmt.Fprintf(&b, "\t%s\n\t\t%s:%d\n", f.Function, f.File, f.Line)
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,100 @@
+package leak
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+func open(t *testing.T, threshold time.Duration) (*Detector, *sql.DB, *time.Time) {
+	t.Helper()
+	s := fakedb.NewScript()
+	s.Query("SELECT").Rows([]string{"n"}, []driver.Value{int64(1)}, []driver.Value{int64(2)})
+	d := New(threshold)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	d.now = func() time.Time { return now }
+	db := sql.OpenDB(d.Wrap(fakedb.NewConnector(s)))
+	t.Cleanup(func() { db.Close() })
+	return d, db, &now
+}
+
+func kinds(leaks []Leak) string {
+	var list []string
+	for _, l := range leaks {
+		list = append(list, string(l.Kind))
+	}
+	return strings.Join(list, ",")
+}
+
+func TestClosed(t *testing.T) {
+	d, db, _ := open(t, 0)
+	ctx := context.Background()
+
+	rows, err := db.QueryContext(ctx, "SELECT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tx.Rollback()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Close()
+
+	if leaks := d.Leaks(); len(leaks) != 0 {
+		t.Errorf("leaks = %s", kinds(leaks))
+	}
+}
+
+func TestLeaks(t *testing.T) {
+	d, db, now := open(t, time.Second)
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	*now = now.Add(time.Second)
+	rows, err := db.QueryContext(ctx, "SELECT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rows.Close()
+
+	// Rowsはthresholdに達していない
+	leaks := d.Leaks()
+	if got := kinds(leaks); got != "conn,tx" {
+		t.Fatalf("leaks = %s", got)
+	}
+	if leaks[0].ConnID != leaks[1].ConnID || leaks[0].Age != time.Second {
+		t.Errorf("leaks = %+v", leaks)
+	}
+	if at := leaks[1].At(); !strings.HasPrefix(at, "leak.TestLeaks (leak_test.go:") {
+		t.Errorf("at = %s", at)
+	}
+
+	*now = now.Add(time.Second)
+	if got := kinds(d.Leaks()); got != "conn,tx,conn,rows" {
+		t.Errorf("leaks = %s", got)
+	}
+
+	var b strings.Builder
+	n, err := d.Report(&b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 4 || !strings.Contains(b.String(), "rows conn=2 age=1s query=\"SELECT\"\n\tgithub.com/ystkg/db-examples/internal/leak.TestLeaks\n") {
+		t.Errorf("n = %d\n%s", n, b.String())
+	}
+}
@@ -0,0 +1,139 @@
+// Package loadgen はコネクションプールを取り合う負荷をかける
+//
+// 複数のgoroutineから同じクエリを実行し、コネクションの取得待ちの時間と
+// タイムアウトによる失敗を集計する
+package loadgen
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Config は負荷のかけ方
+type Config struct {
+	Workers  int           // 同時に実行するgoroutineの数
+	Requests int           // goroutineあたりの実行回数。0の場合は1
+	Query    string        // 実行するクエリ。結果は全て読み込む
+	Args     []any         // クエリのパラメータ
+	Hold     time.Duration // 1レコード目を読み込んだ後、Rowsを開いたまま待つ時間
+	Timeout  time.Duration // 1回あたりのタイムアウト（取得待ちを含む）。0の場合は無制限
+}
+
+// Result は集計結果
+type Result struct {
+	Requests     int
+	Succeeded    int
+	Deadline     int             // context.DeadlineExceededで失敗した数
+	Failed       int             // その他のエラーで失敗した数
+	Acquire      []time.Duration // コネクションを取得できるまでの時間（昇順）
+	WaitCount    int64           // 実行中に増えたDBStats.WaitCount
+	WaitDuration time.Duration   // 実行中に増えたDBStats.WaitDuration
+	Elapsed      time.Duration
+}
+
+// Percentile はAcquireのパーセンタイル（nearest-rank）。取得できたものがない場合は0
+func (r Result) Percentile(p float64) time.Duration {
+	if len(r.Acquire) == 0 {
+		return 0
+	}
+	i := int(math.Ceil(p/100*float64(len(r.Acquire)))) - 1
+	return r.Acquire[min(max(i, 0), len(r.Acquire)-1)]
+}
+
+// Run はConfigに従って負荷をかけ、全てのgoroutineの終了を待って集計する
+//
+// 取得待ちの時間を計るため、コネクションはDB.Connで取得してからクエリを実行する
+// DB.QueryContextなどが内部で行う取得と同じ
+func Run(ctx context.Context, db *sql.DB, c Config) Result {
+	requests := max(c.Requests, 1)
+	before := db.Stats()
+	start := time.Now()
+
+	var mu sync.Mutex
+	var r Result
+	var wg sync.WaitGroup
+	for range c.Workers {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range requests {
+				acquire, ok, err := c.do(ctx, db)
+
+				mu.Lock()
+				r.Requests++
+				if ok {
+					r.Acquire = append(r.Acquire, acquire)
+				}
+				switch {
+				case err == nil:
+					r.Succeeded++
+				case errors.Is(err, context.DeadlineExceeded):
+					r.Deadline++
+				default:
+					r.Failed++
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	after := db.Stats()
+	r.Elapsed = time.Since(start)
+	r.WaitCount = after.WaitCount - before.WaitCount
+	r.WaitDuration = after.WaitDuration - before.WaitDuration
+	slices.Sort(r.Acquire)
+	return r
+}
+
+// do は1回分の実行。コネクションを取得できた場合は取得までの時間とtrueを返す
+func (c Config) do(ctx context.Context, db *sql.DB) (time.Duration, bool, error) {
+	if 0 < c.Timeout {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	acquire := time.Since(start)
+	defer conn.Close()
+
+	return acquire, true, c.query(ctx, conn)
+}
+
+func (c Config) query(ctx context.Context, conn *sql.Conn) error {
+	rows, err := conn.QueryContext(ctx, c.Query, c.Args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for first := true; rows.Next(); first = false {
+		if first && 0 < c.Hold {
+			if err := sleep(ctx, c.Hold); err != nil {
+				return err
+			}
+		}
+	}
+	return rows.Err()
+}
+
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,83 @@
+package loadgen
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+func open(t *testing.T, maxOpen int) *sql.DB {
+	t.Helper()
+	s := fakedb.NewScript()
+	s.Query("SELECT").Rows([]string{"n"}, []driver.Value{int64(1)}, []driver.Value{int64(2)})
+	db := fakedb.Open(s)
+	db.SetMaxOpenConns(maxOpen)
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func TestRunWait(t *testing.T) {
+	db := open(t, 1)
+	hold := 20 * time.Millisecond
+	r := Run(context.Background(), db, Config{Workers: 4, Query: "SELECT", Hold: hold})
+
+	if r.Requests != 4 || r.Succeeded != 4 || r.Deadline != 0 || r.Failed != 0 || len(r.Acquire) != 4 {
+		t.Fatalf("result = %+v", r)
+	}
+	// 1つ目以外は取得待ちになる
+	if r.WaitCount != 3 || r.WaitDuration < 6*hold {
+		t.Errorf("WaitCount = %d, WaitDuration = %s", r.WaitCount, r.WaitDuration)
+	}
+	if p50, p99 := r.Percentile(50), r.Percentile(99); p50 < hold || p99 < 3*hold {
+		t.Errorf("p50 = %s, p99 = %s", p50, p99)
+	}
+}
+
+func TestRunDeadline(t *testing.T) {
+	db := open(t, 1)
+	r := Run(context.Background(), db, Config{Workers: 2, Requests: 2, Query: "SELECT", Hold: time.Second, Timeout: 20 * time.Millisecond})
+
+	// 取得できた方もHoldの途中でタイムアウトになる
+	if r.Requests != 4 || r.Deadline != 4 || r.Succeeded != 0 || r.Failed != 0 {
+		t.Fatalf("result = %+v", r)
+	}
+	if len(r.Acquire) == 0 || len(r.Acquire) == 4 || r.WaitCount == 0 {
+		t.Errorf("Acquire = %d, WaitCount = %d", len(r.Acquire), r.WaitCount)
+	}
+}
+
+func TestRunFailed(t *testing.T) {
+	db := open(t, 1)
+	r := Run(context.Background(), db, Config{Workers: 2, Query: "UPDATE"})
+
+	if r.Requests != 2 || r.Failed != 2 || len(r.Acquire) != 2 {
+		t.Errorf("result = %+v", r)
+	}
+}
+
+func TestPercentile(t *testing.T) {
+	var r Result
+	if p := r.Percentile(99); p != 0 {
+		t.Errorf("empty = %s", p)
+	}
+	for i := range 100 {
+		r.Acquire = append(r.Acquire, time.Duration(i+1)*time.Millisecond)
+	}
+	for _, tt := range []struct {
+		p    float64
+		want time.Duration
+	}{
+		{0, time.Millisecond},
+		{50, 50 * time.Millisecond},
+		{99, 99 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+	} {
+		if got := r.Percentile(tt.p); got != tt.want {
+			t.Errorf("Percentile(%v) = %s, want %s", tt.p, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package mysqlload
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"iter"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 値の中のエスケープが必要な文字
+var escaper = strings.NewReplacer(
+	`\`, `\\`,
+	"\t", `\t`,
+	"\n", `\n`,
+	"\r", `\r`,
+	"\x00", `\0`,
+)
+
+// Encode はseqの各要素をvaluesで1行分の値に変換し、LOAD DATAのデフォルトの形式で読み出すio.ReadCloserを返す
+// 読み出しに合わせて変換するので、全体をメモリに載せない
+// time.Timeはlocに変換してから書き出す（nilの場合は変換しない）
+func Encode[T any](seq iter.Seq[T], loc *time.Location, values func(T) []any) io.ReadCloser {
+	pr, pw := io.Pipe()
+	go func() {
+		w := bufio.NewWriter(pw)
+		for v := range seq {
+			if err := writeRow(w, values(v), loc); err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+		}
+		pw.CloseWithError(w.Flush())
+	}()
+	return pr
+}
+
+func writeRow(w *bufio.Writer, values []any, loc *time.Location) error {
+	for i, v := range values {
+		if 0 < i {
+			w.WriteByte('\t')
+		}
+		if _, err := w.WriteString(format(v, loc)); err != nil {
+			return err
+		}
+	}
+	return w.WriteByte('\n')
+}
+
+func format(v any, loc *time.Location) string {
+	switch v := v.(type) {
+	case nil:
+		return `\N`
+	case string:
+		return escaper.Replace(v)
+	case []byte:
+		if v == nil {
+			return `\N`
+		}
+		return escaper.Replace(string(v))
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case int64:
+		return strconv.FormatInt(v, 10)
+	case time.Time:
+		if loc != nil {
+			v = v.In(loc)
+		}
+		return v.Format("2006-01-02 15:04:05.999999")
+	case fmt.Stringer:
+		return escaper.Replace(v.String())
+	}
+	return escaper.Replace(fmt.Sprint(v))
+}
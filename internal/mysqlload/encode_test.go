@@ -0,0 +1,53 @@
+package mysqlload
+
+import (
+	"io"
+	"slices"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestEncode(t *testing.T) {
+	loc := time.FixedZone("JST", 9*60*60)
+	at := time.Date(2024, 10, 5, 1, 2, 3, 400000000, time.UTC)
+	rows := [][]any{
+		{"A\tB\\C\nD", at, nil},
+		{[]byte("x\x00y"), true, int64(-1), 1.5},
+	}
+	r := Encode(slices.Values(rows), loc, func(row []any) []any { return row })
+	got, err := io.ReadAll(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "A\\tB\\\\C\\nD\t2024-10-05 10:02:03.4\t\\N\n" +
+		"x\\0y\t1\t-1\t1.5\n"
+	if string(got) != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestEncodeClose(t *testing.T) {
+	var produced atomic.Int64
+	seq := func(yield func(int) bool) {
+		for i := 0; ; i++ {
+			produced.Add(1)
+			if !yield(i) {
+				return
+			}
+		}
+	}
+	r := Encode(seq, nil, func(i int) []any { return []any{i} })
+	buf := make([]byte, 10)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		t.Fatal(err)
+	}
+	// 途中でCloseすると生成も止まる
+	r.Close()
+	time.Sleep(10 * time.Millisecond)
+	n := produced.Load()
+	time.Sleep(10 * time.Millisecond)
+	if produced.Load() != n {
+		t.Errorf("still producing: %d -> %d", n, produced.Load())
+	}
+}
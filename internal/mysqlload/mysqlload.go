@@ -0,0 +1,104 @@
+// Package mysqlload はMySQLのLOAD DATA LOCAL INFILEによる一括登録を提供する
+//
+// go-sql-driver/mysqlのRegisterReaderHandlerでio.Readerを登録し、'Reader::名前' で読み込ませる
+// サーバ側のlocal_infileが無効な場合はErrDisabledを返す
+package mysqlload
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrDisabled はサーバ側でLOAD DATA LOCAL INFILEが無効になっていることを表す
+var ErrDisabled = errors.New("mysqlload: local infile is disabled")
+
+// 無効な場合のエラー番号
+var disabledNumbers = []uint16{
+	1148, // ER_NOT_ALLOWED_COMMAND
+	3948, // ER_CLIENT_LOCAL_FILES_DISABLED
+}
+
+var nextName atomic.Int64
+
+// Loader は1つのテーブルへのLOAD DATA LOCAL INFILE
+//
+// 入力はLOAD DATAのデフォルトの形式（タブ区切り、改行終わり、エスケープは\、NULLは\N）
+type Loader struct {
+	Table   string
+	Columns []string
+}
+
+// Warning はSHOW WARNINGSの1行
+type Warning struct {
+	Level   string
+	Code    int
+	Message string
+}
+
+// Result はLOAD DATA 1回分の結果
+type Result struct {
+	Rows     int64 // 登録した行数
+	Warnings []Warning
+}
+
+func (l Loader) query(name string) string {
+	return fmt.Sprintf("LOAD DATA LOCAL INFILE 'Reader::%s' INTO TABLE %s CHARACTER SET utf8mb4 (%s)",
+		name, l.Table, strings.Join(l.Columns, ", "),
+	)
+}
+
+// Load はrの内容を登録する
+// LOCALの場合は不正な値や重複もエラーにならずに警告になるので、同じコネクションでSHOW WARNINGSを実行して返す
+// rがio.Closerの場合は終了時にCloseする
+func (l Loader) Load(ctx context.Context, db *sql.DB, r io.Reader) (Result, error) {
+	if c, ok := r.(io.Closer); ok {
+		defer c.Close()
+	}
+	name := fmt.Sprintf("mysqlload%d", nextName.Add(1))
+	mysql.RegisterReaderHandler(name, func() io.Reader { return r })
+	defer mysql.DeregisterReaderHandler(name)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return Result{}, err
+	}
+	defer conn.Close()
+
+	result, err := conn.ExecContext(ctx, l.query(name))
+	if err != nil {
+		var myErr *mysql.MySQLError
+		if errors.As(err, &myErr) && slices.Contains(disabledNumbers, myErr.Number) {
+			return Result{}, fmt.Errorf("%w: %w", ErrDisabled, err)
+		}
+		return Result{}, err
+	}
+	res := Result{}
+	if res.Rows, err = result.RowsAffected(); err != nil {
+		return Result{}, err
+	}
+
+	rows, err := conn.QueryContext(ctx, "SHOW WARNINGS")
+	if err != nil {
+		return Result{}, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var w Warning
+		if err := rows.Scan(&w.Level, &w.Code, &w.Message); err != nil {
+			return Result{}, err
+		}
+		res.Warnings = append(res.Warnings, w)
+	}
+	if err := rows.Err(); err != nil {
+		return Result{}, err
+	}
+	return res, nil
+}
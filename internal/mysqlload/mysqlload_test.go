@@ -0,0 +1,72 @@
+package mysqlload
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+var movieLoader = Loader{Table: "movie", Columns: []string{"title", "created_at"}}
+
+func TestLoad(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Exec("LOAD DATA").Result(0, 2)
+	s.Query("SHOW WARNINGS").Rows([]string{"Level", "Code", "Message"},
+		[]driver.Value{"Warning", int64(1265), "Data truncated for column 'created_at' at row 2"},
+	)
+	db := fakedb.Open(s)
+	defer db.Close()
+
+	res, err := movieLoader.Load(context.Background(), db, strings.NewReader("A\t2025-04-01\nB\tx\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Rows != 2 || !slices.Equal(res.Warnings, []Warning{{"Warning", 1265, "Data truncated for column 'created_at' at row 2"}}) {
+		t.Errorf("result = %+v", res)
+	}
+
+	calls := s.Calls()
+	var queries []string
+	for _, c := range calls {
+		if c.Op == fakedb.OpExec || c.Op == fakedb.OpQuery {
+			queries = append(queries, c.Query)
+		}
+	}
+	if len(queries) != 2 ||
+		!strings.HasPrefix(queries[0], "LOAD DATA LOCAL INFILE 'Reader::mysqlload") ||
+		!strings.HasSuffix(queries[0], "' INTO TABLE movie CHARACTER SET utf8mb4 (title, created_at)") ||
+		queries[1] != "SHOW WARNINGS" {
+		t.Errorf("queries = %q", queries)
+	}
+	// SHOW WARNINGSはLOAD DATAと同じコネクションで実行する
+	if conns := slices.Compact([]int64{calls[1].ConnID, calls[len(calls)-1].ConnID}); len(conns) != 1 {
+		t.Errorf("connections = %v", conns)
+	}
+}
+
+func TestLoadDisabled(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Exec("LOAD DATA").Err(&mysql.MySQLError{Number: 3948, Message: "Loading local data is disabled; this must be enabled on both the client and server sides"})
+	db := fakedb.Open(s)
+	defer db.Close()
+
+	// 読み込まれなかったio.ReadCloserも閉じる
+	r := Encode(slices.Values([]string{"A"}), nil, func(v string) []any { return []any{v} })
+	_, err := movieLoader.Load(context.Background(), db, r)
+	if !errors.Is(err, ErrDisabled) {
+		t.Errorf("err = %v", err)
+	}
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		t.Errorf("err = %v, want wrapped *mysql.MySQLError", err)
+	}
+	if _, err := r.Read(make([]byte, 1)); err == nil {
+		t.Error("reader is not closed")
+	}
+}
@@ -0,0 +1,198 @@
+// Package pgbulk はPostgreSQLへの大量レコードの一括登録を提供する
+//
+// 複数レコードのINSERTに加えて、pgxのコネクションではCOPYとpgx.Batchを使える
+package pgbulk
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/ystkg/db-examples/internal/dialect"
+)
+
+// Method は一括登録の方法
+type Method int
+
+const (
+	Insert Method = iota // 複数レコードのINSERT（database/sql）
+	Copy                 // COPY FROM STDIN（pgx.Conn.CopyFrom）
+	Batch                // 1レコードずつのINSERTをpgx.Batchでまとめて送信
+)
+
+func (m Method) String() string {
+	switch m {
+	case Insert:
+		return "insert"
+	case Copy:
+		return "copy"
+	case Batch:
+		return "batch"
+	}
+	return fmt.Sprintf("Method(%d)", int(m))
+}
+
+// ErrNotPgx はコネクションがpgxでないことを表す
+var ErrNotPgx = errors.New("pgbulk: not a pgx connection")
+
+// Loader は1つのテーブルへの一括登録
+// テーブル名とカラム名はどの方法でも引用符で囲む。Tableは "スキーマ.テーブル" でもよい
+type Loader struct {
+	Table     string
+	Key       string   // 採番される主キー。Insertで使う
+	Columns   []string // 登録するカラム
+	ChunkRows int      // Insertの1つのINSERT文の最大レコード数。0の場合はパラメータ数の上限から求める
+}
+
+// Result は一括登録1回分の結果
+type Result struct {
+	Requested Method
+	Method    Method // 実際に使った方法。pgx以外ではInsertになる
+	Rows      int64
+	Duration  time.Duration
+}
+
+// Fallback はRequestedと異なる方法で登録したかどうか
+func (r Result) Fallback() bool {
+	return r.Requested != r.Method
+}
+
+// Load はrowsをColumnsの順で登録する。全て登録するか、全て登録しないかのどちらか
+// CopyとBatchはpgxのコネクションでのみ使えるので、pq（lib/pq）などではInsertで登録する
+func (l Loader) Load(ctx context.Context, db *sql.DB, m Method, rows [][]any) (Result, error) {
+	start := time.Now()
+	res := Result{Requested: m, Method: m}
+	var err error
+	switch m {
+	case Insert:
+		res.Rows, err = l.insert(ctx, db, rows)
+	case Copy, Batch:
+		err = WithConn(ctx, db, func(conn *pgx.Conn) error {
+			var err error
+			if m == Copy {
+				res.Rows, err = l.CopyFrom(ctx, conn, rows)
+			} else {
+				res.Rows, err = l.SendBatch(ctx, conn, rows)
+			}
+			return err
+		})
+		if errors.Is(err, ErrNotPgx) {
+			res.Method = Insert
+			res.Rows, err = l.insert(ctx, db, rows)
+		}
+	default:
+		err = fmt.Errorf("pgbulk: unknown method:%d", int(m))
+	}
+	res.Duration = time.Since(start)
+	return res, err
+}
+
+func (l Loader) insert(ctx context.Context, db *sql.DB, rows [][]any) (int64, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	n := int64(0)
+	b := dialect.BulkInsert{Table: l.Table, Key: l.Key, Columns: l.Columns, ChunkRows: l.ChunkRows}
+	if err := b.Exec(ctx, tx, dialect.Postgres, rows, func(*sql.Rows) error {
+		n++
+		return nil
+	}); err != nil {
+		return 0, err
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// CopyFrom はCOPY FROM STDINでrowsを登録する
+func (l Loader) CopyFrom(ctx context.Context, conn *pgx.Conn, rows [][]any) (int64, error) {
+	return conn.CopyFrom(ctx, l.table(), l.Columns, pgx.CopyFromRows(rows))
+}
+
+// table はTableをdialect.Postgres.QuoteIdentと同じく "." で区切った識別子
+func (l Loader) table() pgx.Identifier {
+	return pgx.Identifier(strings.Split(l.Table, "."))
+}
+
+// batchQuery はSendBatchで1レコードずつ実行するINSERT
+func (l Loader) batchQuery() string {
+	columns := make([]string, len(l.Columns))
+	for i, c := range l.Columns {
+		columns[i] = dialect.Postgres.QuoteIdent(c)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", l.table().Sanitize(), strings.Join(columns, ", "), dialect.Postgres.In(1, len(l.Columns)))
+}
+
+// SendBatch は1レコードずつのINSERTをまとめて送信する
+// トランザクション外で送信した場合も暗黙のトランザクションで実行される
+func (l Loader) SendBatch(ctx context.Context, conn *pgx.Conn, rows [][]any) (int64, error) {
+	query := l.batchQuery()
+	batch := &pgx.Batch{}
+	for _, row := range rows {
+		batch.Queue(query, row...)
+	}
+	br := conn.SendBatch(ctx, batch)
+	n := int64(0)
+	for range rows {
+		tag, err := br.Exec()
+		if err != nil {
+			br.Close()
+			return 0, err
+		}
+		n += tag.RowsAffected()
+	}
+	if err := br.Close(); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// WithConn はdbのコネクションを1つ確保し、pgxのコネクションとしてfに渡す
+// pgx/v5/stdlibではAcquireConnではなくsql.Conn.Rawを使う
+// ラップされたコネクションはUnwrapで辿る。pgxでない場合はErrNotPgxを返す
+func WithConn(ctx context.Context, db *sql.DB, f func(conn *pgx.Conn) error) error {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		for {
+			switch c := driverConn.(type) {
+			case *stdlib.Conn:
+				return f(c.Conn())
+			case interface{ Unwrap() driver.Conn }:
+				driverConn = c.Unwrap()
+			default:
+				return fmt.Errorf("%w: %T", ErrNotPgx, driverConn)
+			}
+		}
+	})
+}
+
+// WriteTable は結果を表形式で出力する
+func WriteTable(w io.Writer, results []Result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "method\tused\trows\tduration\trows/s")
+	for _, r := range results {
+		perSec := 0.0
+		if 0 < r.Duration {
+			perSec = float64(r.Rows) / r.Duration.Seconds()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%s\t%.0f\n", r.Requested, r.Method, r.Rows, r.Duration.Round(time.Microsecond), perSec)
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,106 @@
+package pgbulk
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/fakedb"
+	"github.com/ystkg/db-examples/internal/sqltrace"
+)
+
+var movieLoader = Loader{
+	Table:     "movie",
+	Key:       "id",
+	Columns:   []string{"title", "created_at"},
+	ChunkRows: 2,
+}
+
+var movieRows = [][]any{{"A", "2025-04-01"}, {"B", "2025-04-01"}, {"C", "2025-04-01"}}
+
+func TestLoadFallback(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query(`INSERT INTO "movie"`).Rows([]string{"id"}, []driver.Value{int64(1)}, []driver.Value{int64(2)}).Once()
+	s.Query(`INSERT INTO "movie"`).Rows([]string{"id"}, []driver.Value{int64(3)})
+	// ラップされたコネクションもUnwrapで辿ってpgxかどうかを判定する
+	db := sql.OpenDB(sqltrace.Wrap(fakedb.NewConnector(s), func(sqltrace.Event) {}))
+	defer db.Close()
+
+	res, err := movieLoader.Load(context.Background(), db, Copy, movieRows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Requested != Copy || res.Method != Insert || !res.Fallback() || res.Rows != 3 {
+		t.Errorf("result = %+v", res)
+	}
+	want := []string{
+		"Begin",
+		`Query INSERT INTO "movie" ("title", "created_at") VALUES ($1, $2), ($3, $4) RETURNING "id"`,
+		`Query INSERT INTO "movie" ("title", "created_at") VALUES ($1, $2) RETURNING "id"`,
+		"Commit",
+	}
+	if got := s.Trace(); !slices.Equal(got, want) {
+		t.Errorf("trace\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestWithConnNotPgx(t *testing.T) {
+	db := fakedb.Open(fakedb.NewScript())
+	defer db.Close()
+
+	err := WithConn(context.Background(), db, func(*pgx.Conn) error {
+		t.Error("f called")
+		return nil
+	})
+	if !errors.Is(err, ErrNotPgx) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestLoadUnknownMethod(t *testing.T) {
+	db := fakedb.Open(fakedb.NewScript())
+	defer db.Close()
+
+	if _, err := movieLoader.Load(context.Background(), db, Method(9), movieRows); err == nil {
+		t.Error("want error")
+	}
+}
+
+func TestWriteTable(t *testing.T) {
+	var b strings.Builder
+	err := WriteTable(&b, []Result{
+		{Requested: Insert, Method: Insert, Rows: 5000, Duration: 250 * time.Millisecond},
+		{Requested: Copy, Method: Copy, Rows: 5000, Duration: 50 * time.Millisecond},
+		{Requested: Batch, Method: Insert, Rows: 5000, Duration: 0},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"method  used    rows  duration  rows/s\n" +
+		"insert  insert  5000  250ms     20000\n" +
+		"copy    copy    5000  50ms      100000\n" +
+		"batch   insert  5000  0s        0\n"
+	if b.String() != want {
+		t.Errorf("table\n got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestIdentifier(t *testing.T) {
+	l := Loader{Table: "public.Movie", Columns: []string{"title", "order"}}
+	// CopyFromとSendBatchでテーブル名の扱いを揃える
+	if got := l.table().Sanitize(); got != dialect.Postgres.QuoteIdent(l.Table) {
+		t.Errorf("table = %s", got)
+	}
+	want := `INSERT INTO "public"."Movie" ("title", "order") VALUES ($1,$2)`
+	if got := l.batchQuery(); got != want {
+		t.Errorf("\n got: %s\nwant: %s", got, want)
+	}
+}
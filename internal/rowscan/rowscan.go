@@ -0,0 +1,201 @@
+// Package rowscan はSELECTの結果を構造体に読み込む
+//
+// カラムはフィールドのタグ db:"カラム名" で対応付ける
+// タグがないフィールドはフィールド名を小文字にしたカラム名、db:"-" のフィールドは対象外
+// 埋め込みの構造体のフィールドも対象にする。同じカラム名は浅いフィールドを優先する（encoding/jsonと同じ）
+// 埋め込みのポインタ（*T）はnilの場合に読み込めないのでエラー
+//
+// sql.Null* やポインタのフィールドは、database/sqlのScanと同様にNULLを扱える
+// 対応するフィールドがないカラムはエラー。対応するカラムがないフィールドはエラーにならずゼロ値のまま
+// SELECTのカラムの書き漏らしは検出できないので、必要なカラムはテストで確認する
+//
+// 型パラメータが構造体以外（sql.Scannerやtime.Timeを含む）の場合は1カラムの結果をそのまま読み込む
+package rowscan
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"iter"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// One はrowsの現在の行をTに読み込む。rows.Nextの後に呼び出す
+func One[T any](rows *sql.Rows) (T, error) {
+	var v T
+	columns, err := rows.Columns()
+	if err != nil {
+		return v, err
+	}
+	dest, err := fields(&v, columns)
+	if err != nil {
+		return v, err
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return v, fmt.Errorf("rowscan: %T: %w", v, err)
+	}
+	return v, nil
+}
+
+// All はrowsの全ての行をTに読み込んで順に返す。rowsは最後にクローズする
+// エラーが発生した場合はゼロ値とエラーを返して終了する
+func All[T any](rows *sql.Rows) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		defer rows.Close()
+
+		var zero T
+		columns, err := rows.Columns()
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		for rows.Next() {
+			var v T
+			dest, err := fields(&v, columns)
+			if err == nil {
+				if err = rows.Scan(dest...); err != nil {
+					err = fmt.Errorf("rowscan: %T: %w", v, err)
+				}
+			}
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(v, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+			return
+		}
+		if err := rows.Close(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// Collect はrowsの全ての行をTに読み込んでスライスで返す。rowsはクローズする
+func Collect[T any](rows *sql.Rows) ([]T, error) {
+	var list []T
+	for v, err := range All[T](rows) {
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// Row はQueryRowContextの結果をTに読み込む
+// *sql.Rowからはカラム名を取得できないので、SELECTのカラムの順にcolumnsで指定する
+func Row[T any](row *sql.Row, columns ...string) (T, error) {
+	var v T
+	dest, err := fields(&v, columns)
+	if err != nil {
+		return v, err
+	}
+	if err := row.Scan(dest...); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return v, err
+		}
+		return v, fmt.Errorf("rowscan: %T: %w", v, err)
+	}
+	return v, nil
+}
+
+var (
+	scannerType = reflect.TypeFor[sql.Scanner]()
+	timeType    = reflect.TypeFor[time.Time]()
+)
+
+// fields はcolumnsの順にvのフィールドのポインタを返す
+// columnsにないフィールドは対象にしない（ゼロ値のまま）
+func fields(v any, columns []string) ([]any, error) {
+	rv := reflect.ValueOf(v).Elem()
+	t := rv.Type()
+	if t.Kind() != reflect.Struct || t == timeType || reflect.PointerTo(t).Implements(scannerType) {
+		if len(columns) != 1 {
+			return nil, fmt.Errorf("rowscan: %s: %d columns, want 1", t, len(columns))
+		}
+		return []any{v}, nil
+	}
+
+	index, err := collect(t)
+	if err != nil {
+		return nil, err
+	}
+	dest := make([]any, len(columns))
+	for i, c := range columns {
+		idx, ok := index[strings.ToLower(c)]
+		if !ok {
+			return nil, fmt.Errorf("rowscan: %s: no field for column %q", t, c)
+		}
+		f, err := rv.FieldByIndexErr(idx)
+		if err != nil {
+			return nil, fmt.Errorf("rowscan: %s: column %q: %w", t, c, err)
+		}
+		dest[i] = f.Addr().Interface()
+	}
+	return dest, nil
+}
+
+// collect はtのフィールドのカラム名とインデックスを返す
+// 埋め込みの構造体は深さの順に幅優先でたどり、同じカラム名は浅いフィールドを優先する
+// 同じ深さで重複する場合は先に宣言されたフィールドを優先する
+func collect(t reflect.Type) (map[string][]int, error) {
+	index := map[string][]int{}
+	type level struct {
+		t     reflect.Type
+		index []int
+	}
+	current := []level{{t: t}}
+	for len(current) > 0 {
+		var next []level
+		found := map[string][]int{} // この深さで見つけたカラム
+		for _, l := range current {
+			for i := range l.t.NumField() {
+				f := l.t.Field(i)
+				if !f.IsExported() && !f.Anonymous {
+					continue
+				}
+				tag := f.Tag.Get("db")
+				if tag == "-" {
+					continue
+				}
+				idx := append(append([]int{}, l.index...), i)
+				if f.Anonymous && tag == "" {
+					ft := f.Type
+					if ft.Kind() == reflect.Pointer && ft.Elem().Kind() == reflect.Struct && !ft.Implements(scannerType) {
+						return nil, fmt.Errorf("rowscan: %s: embedded pointer %s is not supported", t, ft)
+					}
+					if ft.Kind() == reflect.Struct && !reflect.PointerTo(ft).Implements(scannerType) {
+						next = append(next, level{t: ft, index: idx})
+						continue
+					}
+				}
+				if !f.IsExported() {
+					continue
+				}
+				name := tag
+				if name == "" {
+					name = f.Name
+				}
+				name = strings.ToLower(name)
+				if _, dup := index[name]; dup {
+					continue // 浅いフィールドがある
+				}
+				if _, dup := found[name]; !dup {
+					found[name] = idx
+				}
+			}
+		}
+		for name, idx := range found {
+			index[name] = idx
+		}
+		current = next
+	}
+	return index, nil
+}
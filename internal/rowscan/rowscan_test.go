@@ -0,0 +1,144 @@
+package rowscan
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+type timestamps struct {
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt *time.Time
+}
+
+type movie struct {
+	ID    int32 `db:"id"`
+	Title sql.NullString
+	Note  *string `db:"note"`
+	Skip  string  `db:"-"`
+	timestamps
+}
+
+var at = time.Date(2025, 4, 1, 9, 0, 0, 0, time.UTC)
+
+func query(t *testing.T, columns []string, rows ...[]driver.Value) *sql.Rows {
+	t.Helper()
+	s := fakedb.NewScript()
+	s.Query("SELECT").Rows(columns, rows...)
+	db := fakedb.Open(s)
+	t.Cleanup(func() { db.Close() })
+	r, err := db.QueryContext(context.Background(), "SELECT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestCollect(t *testing.T) {
+	rows := query(t, []string{"id", "title", "note", "created_at", "UpdatedAt"},
+		[]driver.Value{int64(1), "A", "memo", at, at},
+		[]driver.Value{int64(2), nil, nil, at, nil},
+	)
+	movies, err := Collect[movie](rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(movies) != 2 {
+		t.Fatalf("len = %d", len(movies))
+	}
+	if m := movies[0]; m.ID != 1 || m.Title.String != "A" || *m.Note != "memo" || !m.CreatedAt.Equal(at) || !m.UpdatedAt.Equal(at) {
+		t.Errorf("movies[0] = %+v", m)
+	}
+	if m := movies[1]; m.Title.Valid || m.Note != nil || m.UpdatedAt != nil {
+		t.Errorf("movies[1] = %+v", m)
+	}
+}
+
+func TestAllBreak(t *testing.T) {
+	rows := query(t, []string{"id"}, []driver.Value{int64(1)}, []driver.Value{int64(2)})
+	for id, err := range All[int32](rows) {
+		if err != nil || id != 1 {
+			t.Fatalf("id = %d, err = %v", id, err)
+		}
+		break
+	}
+	if rows.Next() {
+		t.Error("rows is not closed")
+	}
+}
+
+func TestMismatch(t *testing.T) {
+	rows := query(t, []string{"id", "rating"}, []driver.Value{int64(1), int64(5)})
+	for _, err := range All[movie](rows) {
+		if err == nil || !strings.Contains(err.Error(), `no field for column "rating"`) {
+			t.Errorf("err = %v", err)
+		}
+	}
+
+	rows = query(t, []string{"id", "title"}, []driver.Value{"x", "A"})
+	for _, err := range All[movie](rows) {
+		if err == nil || !strings.Contains(err.Error(), "rowscan: rowscan.movie:") {
+			t.Errorf("err = %v", err)
+		}
+	}
+
+	rows = query(t, []string{"id", "title"}, []driver.Value{int64(1), "A"})
+	if _, err := Collect[int32](rows); err == nil || !strings.Contains(err.Error(), "2 columns, want 1") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+type inner struct {
+	Title string `db:"title"`
+}
+
+type middle struct {
+	inner
+	Note string `db:"note"`
+}
+
+type other struct {
+	Title string `db:"title"`
+}
+
+// deep はmiddleを先に埋め込むが、titleは浅いotherのフィールドを優先する
+type deep struct {
+	middle
+	other
+}
+
+func TestEmbedded(t *testing.T) {
+	rows := query(t, []string{"title", "note"}, []driver.Value{"A", "memo"})
+	list, err := Collect[deep](rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := list[0]; v.other.Title != "A" || v.inner.Title != "" || v.Note != "memo" {
+		t.Errorf("v = %+v", v)
+	}
+
+	type pointer struct {
+		*inner
+	}
+	rows = query(t, []string{"title"}, []driver.Value{"A"})
+	if _, err := Collect[pointer](rows); err == nil || !strings.Contains(err.Error(), "embedded pointer *rowscan.inner is not supported") {
+		t.Errorf("err = %v", err)
+	}
+}
+
+func TestMissingColumn(t *testing.T) {
+	// 対応するカラムがないフィールドはエラーにならずゼロ値のまま
+	rows := query(t, []string{"id"}, []driver.Value{int64(1)})
+	movies, err := Collect[movie](rows)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m := movies[0]; m.ID != 1 || m.Title.Valid || m.Note != nil || !m.CreatedAt.IsZero() || m.UpdatedAt != nil {
+		t.Errorf("m = %+v", m)
+	}
+}
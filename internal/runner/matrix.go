@@ -0,0 +1,215 @@
+package runner
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/sqltrace"
+)
+
+// サーバ側に作成されたプリペアドステートメントの確認（確認用のSQL自体は除外）
+const pgPreparedStatements = "SELECT name FROM pg_prepared_statements WHERE statement NOT LIKE '%pg_prepared_statements%' ORDER BY prepare_time, name"
+
+// MatrixRun はドライバ1つ分の実行で観測した内容
+type MatrixRun struct {
+	Driver string
+	Lines  []string
+	Err    error
+}
+
+// Matrix はPostgreSQLのサンプルを対応している全てのドライバで実行する
+// Eitherのサンプルはmysqlも含めて実行する
+func Matrix(ctx context.Context, setup Setup, e Example) ([]MatrixRun, error) {
+	if e.Target != Pg && e.Target != Either {
+		return nil, fmt.Errorf("%s: matrix supports only %s and %s", e.Name, Pg, Either)
+	}
+	var runs []MatrixRun
+	for _, driverName := range e.Drivers {
+		// プリペアドステートメントの確認はPostgreSQLで実行する場合だけ
+		rec := &recorder{pg: driverName != dbsetup.DriverMySQL, seen: map[int64]map[string]bool{}}
+		wrapped := dbsetup.WithWrapper(ctx, func(c driver.Connector) driver.Connector {
+			return sqltrace.Wrap(c, rec.observe)
+		})
+		// セットアップ処理の呼び出しは記録しない
+		err := runOne(wrapped, setup, Example{
+			Name:   e.Name,
+			Target: e.Target,
+			Run: func(ctx context.Context, dbs DBs) error {
+				rec.start()
+				return e.Run(ctx, dbs)
+			},
+		}, driverName)
+		rec.add("return " + errorType(err))
+		runs = append(runs, MatrixRun{Driver: driverName, Lines: rec.lines, Err: err})
+	}
+	return runs, nil
+}
+
+type recorder struct {
+	pg        bool // pg_prepared_statementsを確認する
+	mu        sync.Mutex
+	recording bool
+	lines     []string
+	seen      map[int64]map[string]bool
+}
+
+func (r *recorder) start() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.recording = true
+}
+
+func (r *recorder) add(line string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.lines = append(r.lines, line)
+}
+
+func (r *recorder) observe(ev sqltrace.Event) {
+	r.mu.Lock()
+	recording := r.recording
+	r.mu.Unlock()
+
+	switch ev.Op {
+	case sqltrace.OpConnect, sqltrace.OpClose, sqltrace.OpStart, sqltrace.OpResetSession, sqltrace.OpCheckin:
+		return
+	}
+	if recording {
+		r.add(describeEvent(ev))
+	}
+	switch ev.Op {
+	case sqltrace.OpPrepare, sqltrace.OpExec, sqltrace.OpRowsClose, sqltrace.OpCommit, sqltrace.OpRollback:
+		if r.pg && ev.Err == nil {
+			r.probe(ev, recording)
+		}
+	}
+}
+
+// probe はサーバ側で新たに作成されたプリペアドステートメントを記録する
+func (r *recorder) probe(ev sqltrace.Event, recording bool) {
+	q, ok := ev.Conn.(driver.QueryerContext)
+	if !ok {
+		return
+	}
+	rows, err := q.QueryContext(context.Background(), pgPreparedStatements, nil)
+	if err != nil {
+		if recording {
+			r.add("server-stmt " + errorType(err))
+		}
+		return
+	}
+	defer rows.Close()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	seen := r.seen[ev.ConnID]
+	if seen == nil {
+		seen = map[string]bool{}
+		r.seen[ev.ConnID] = seen
+	}
+	dest := make([]driver.Value, 1)
+	for rows.Next(dest) == nil {
+		name := fmt.Sprintf("%s", dest[0])
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if recording {
+			r.lines = append(r.lines, "server-stmt "+name)
+		}
+	}
+}
+
+func describeEvent(ev sqltrace.Event) string {
+	query := strings.Join(strings.Fields(ev.Query), " ")
+	switch ev.Op {
+	case sqltrace.OpExec:
+		if ev.Err != nil {
+			return fmt.Sprintf("exec %s args=%d -> %s", query, ev.Args, errorType(ev.Err))
+		}
+		rowsAffected, errRowsAffected := ev.Result.RowsAffected()
+		lastInsertId, errLastInsertId := ev.Result.LastInsertId()
+		return fmt.Sprintf("exec %s args=%d -> rowsAffected=%s lastInsertId=%s", query, ev.Args,
+			valueOrError(rowsAffected, errRowsAffected), valueOrError(lastInsertId, errLastInsertId),
+		)
+	case sqltrace.OpQuery:
+		return fmt.Sprintf("query %s args=%d -> %s", query, ev.Args, errorType(ev.Err))
+	case sqltrace.OpRowsClose:
+		return fmt.Sprintf("rows %s -> %d rows", query, ev.Rows)
+	case sqltrace.OpPrepare, sqltrace.OpStmtClose:
+		return fmt.Sprintf("%s %s -> %s", ev.Op, query, errorType(ev.Err))
+	}
+	return fmt.Sprintf("%s -> %s", ev.Op, errorType(ev.Err))
+}
+
+func errorType(err error) string {
+	if err == nil {
+		return "ok"
+	}
+	return fmt.Sprintf("error(%T)", err)
+}
+
+func valueOrError(v int64, err error) string {
+	if err != nil {
+		return errorType(err)
+	}
+	return fmt.Sprint(v)
+}
+
+// Diff は2つの実行結果の差分を出力し、差分の行数を返す
+func Diff(w io.Writer, a, b MatrixRun) int {
+	fmt.Fprintf(w, "--- %s\n+++ %s\n", a.Driver, b.Driver)
+	n := 0
+	for _, l := range diffLines(a.Lines, b.Lines) {
+		if l[0] != ' ' {
+			n++
+		}
+		fmt.Fprintln(w, l)
+	}
+	return n
+}
+
+// diffLines は最長共通部分列による行単位の差分
+func diffLines(a, b []string) []string {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var out []string
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			out = append(out, "  "+a[i])
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		out = append(out, "- "+a[i])
+	}
+	for ; j < len(b); j++ {
+		out = append(out, "+ "+b[j])
+	}
+	return out
+}
@@ -0,0 +1,128 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/fakedb"
+	"github.com/ystkg/db-examples/internal/sqltrace"
+)
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		a, b []string
+		want []string
+	}{
+		{nil, nil, nil},
+		{[]string{"a", "b"}, []string{"a", "b"}, []string{"  a", "  b"}},
+		{[]string{"a"}, nil, []string{"- a"}},
+		{nil, []string{"a"}, []string{"+ a"}},
+		{[]string{"a", "b", "c"}, []string{"a", "x", "c"}, []string{"  a", "- b", "+ x", "  c"}},
+		{[]string{"a", "b", "c", "d"}, []string{"b", "d", "e"}, []string{"- a", "  b", "- c", "  d", "+ e"}},
+		{[]string{"prepare", "exec", "close"}, []string{"exec"}, []string{"- prepare", "  exec", "- close"}},
+	}
+	for _, tt := range tests {
+		got := diffLines(tt.a, tt.b)
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("%v %v\n got: %q\nwant: %q", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDescribeEvent(t *testing.T) {
+	errSyntax := errors.New("syntax error")
+	tests := []struct {
+		ev   sqltrace.Event
+		want string
+	}{
+		{sqltrace.Event{Op: sqltrace.OpExec, Query: "UPDATE shop\n\tSET name = $1", Args: 1, Result: driver.RowsAffected(2)},
+			"exec UPDATE shop SET name = $1 args=1 -> rowsAffected=2 lastInsertId=error(*errors.errorString)"},
+		{sqltrace.Event{Op: sqltrace.OpExec, Query: "UPDATE", Err: errSyntax}, "exec UPDATE args=0 -> error(*errors.errorString)"},
+		{sqltrace.Event{Op: sqltrace.OpQuery, Query: "SELECT 1"}, "query SELECT 1 args=0 -> ok"},
+		{sqltrace.Event{Op: sqltrace.OpRowsClose, Query: "SELECT 1", Rows: 3}, "rows SELECT 1 -> 3 rows"},
+		{sqltrace.Event{Op: sqltrace.OpPrepare, Query: "SELECT $1"}, "prepare SELECT $1 -> ok"},
+		{sqltrace.Event{Op: sqltrace.OpStmtClose, Query: "SELECT $1"}, "stmt-close SELECT $1 -> ok"},
+		{sqltrace.Event{Op: sqltrace.OpCommit, Err: errSyntax}, "commit -> error(*errors.errorString)"},
+	}
+	for _, tt := range tests {
+		if got := describeEvent(tt.ev); got != tt.want {
+			t.Errorf("\n got: %s\nwant: %s", got, tt.want)
+		}
+	}
+}
+
+// noLastInsertId はpgxやpqのようにLastInsertIdに対応していない結果
+type noLastInsertId struct{}
+
+func (noLastInsertId) LastInsertId() (int64, error) {
+	return 0, errors.New("LastInsertId is not supported by this driver")
+}
+
+func (noLastInsertId) RowsAffected() (int64, error) {
+	return 1, nil
+}
+
+func TestMatrixDiff(t *testing.T) {
+	pg := fakedb.NewScript()
+	pg.Exec("INSERT").DriverResult(noLastInsertId{})
+	pg.Query("pg_prepared_statements").Rows([]string{"name"}, []driver.Value{"stmtcache_1"})
+	my := fakedb.NewScript()
+	my.Exec("INSERT").Result(100, 1)
+	setup := Setup{
+		Pg: func(ctx context.Context, driverName string) (*sql.DB, error) {
+			return sql.OpenDB(dbsetup.Wrap(ctx, fakedb.NewConnector(pg))), nil
+		},
+		MySQL: func(ctx context.Context) (*sql.DB, error) {
+			return sql.OpenDB(dbsetup.Wrap(ctx, fakedb.NewConnector(my))), nil
+		},
+	}
+	e := Example{
+		Name:    "Ex01Insert",
+		Target:  Either,
+		Drivers: []string{dbsetup.DriverPgx, dbsetup.DriverMySQL},
+		Run: func(ctx context.Context, dbs DBs) error {
+			db := dbs.Pg
+			if db == nil {
+				db = dbs.MySQL
+			}
+			_, err := db.ExecContext(ctx, "INSERT INTO shop (name) VALUES ('shop1')")
+			return err
+		},
+	}
+
+	runs, err := Matrix(context.Background(), setup, e)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(runs) != 2 || runs[0].Driver != dbsetup.DriverPgx || runs[1].Driver != dbsetup.DriverMySQL {
+		t.Fatalf("runs = %+v", runs)
+	}
+
+	var b strings.Builder
+	n := Diff(&b, runs[0], runs[1])
+	want := "--- pgx\n+++ mysql\n" +
+		"- exec INSERT INTO shop (name) VALUES ('shop1') args=0 -> rowsAffected=1 lastInsertId=error(*errors.errorString)\n" +
+		"- server-stmt stmtcache_1\n" +
+		"+ exec INSERT INTO shop (name) VALUES ('shop1') args=0 -> rowsAffected=1 lastInsertId=100\n" +
+		"  return ok\n"
+	if got := b.String(); got != want || n != 3 {
+		t.Errorf("n = %d\n got:\n%s\nwant:\n%s", n, got, want)
+	}
+	// MySQLではpg_prepared_statementsを確認しない
+	for _, c := range my.Calls() {
+		if strings.Contains(c.Query, "pg_prepared_statements") {
+			t.Errorf("probed on mysql: %s", c.Query)
+		}
+	}
+}
+
+func TestMatrixTarget(t *testing.T) {
+	if _, err := Matrix(context.Background(), Setup{}, Example{Name: "Ex01MySQL", Target: MySQL}); err == nil {
+		t.Error("no error")
+	}
+}
@@ -0,0 +1,139 @@
+// Package runner はサンプルの登録と実行を提供する
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/dialect"
+)
+
+// Target はサンプルが必要とするデータベース
+type Target string
+
+const (
+	Pg     Target = "pg"
+	MySQL  Target = "mysql"
+	Both   Target = "both"   // PostgreSQLとMySQLの両方
+	Either Target = "either" // PostgreSQLとMySQLのどちらか。ドライバで選択する
+)
+
+var ErrUnknown = errors.New("unknown")
+
+// DBs はサンプルに渡すデータベース。Targetに応じて必要なものだけが設定される
+type DBs struct {
+	Pg     *sql.DB
+	MySQL  *sql.DB
+	Driver string // 選択されたSQLドライバ名（Bothの場合はPostgreSQLのもの）
+}
+
+// Func はサンプルの実行関数
+type Func func(ctx context.Context, dbs DBs) error
+
+// Example は登録されたサンプル
+type Example struct {
+	Name        string
+	Target      Target
+	Description string
+	Drivers     []string // 対応するSQLドライバ名。先頭がデフォルト
+	ExpectErr   error    // 失敗することが想定されている場合のエラー
+	Run         Func
+}
+
+// Single はデータベースを1つ使うサンプルをFuncに変換する
+func Single(f func(ctx context.Context, db *sql.DB) error) Func {
+	return func(ctx context.Context, dbs DBs) error {
+		if dbs.Pg != nil {
+			return f(ctx, dbs.Pg)
+		}
+		return f(ctx, dbs.MySQL)
+	}
+}
+
+// Pair はPostgreSQLとMySQLの両方を使うサンプルをFuncに変換する
+func Pair(f func(ctx context.Context, pgDB, myDB *sql.DB) error) Func {
+	return func(ctx context.Context, dbs DBs) error {
+		return f(ctx, dbs.Pg, dbs.MySQL)
+	}
+}
+
+// Portable はDialectを使ってPostgreSQLとMySQLのどちらでも動くサンプルをFuncに変換する
+func Portable(f func(ctx context.Context, db *sql.DB, d dialect.Dialect) error) Func {
+	return func(ctx context.Context, dbs DBs) error {
+		d, err := dialect.For(dbs.Driver)
+		if err != nil {
+			return err
+		}
+		if dbs.Pg != nil {
+			return f(ctx, dbs.Pg, d)
+		}
+		return f(ctx, dbs.MySQL, d)
+	}
+}
+
+var examples = map[string]Example{}
+
+// Register はサンプルを登録する。名前は大文字小文字の区別なしで重複不可
+func Register(e Example) {
+	key := strings.ToLower(e.Name)
+	if _, dup := examples[key]; dup {
+		panic("runner: Register called twice for " + e.Name)
+	}
+	if e.Run == nil {
+		panic("runner: Register with nil Run for " + e.Name)
+	}
+	if len(e.Drivers) == 0 {
+		switch e.Target {
+		case MySQL:
+			e.Drivers = []string{dbsetup.DriverMySQL}
+		case Either:
+			e.Drivers = []string{dbsetup.DriverPgx, dbsetup.DriverPq, dbsetup.DriverMySQL}
+		default:
+			e.Drivers = []string{dbsetup.DriverPgx}
+		}
+	}
+	examples[key] = e
+}
+
+// Lookup は名前でサンプルを探す。大文字小文字の区別なし
+func Lookup(name string) (Example, error) {
+	e, ok := examples[strings.ToLower(name)]
+	if !ok {
+		return Example{}, fmt.Errorf("%w:%s", ErrUnknown, name)
+	}
+	return e, nil
+}
+
+// Examples は登録されたサンプルを名前順で返す
+func Examples() []Example {
+	list := make([]Example, 0, len(examples))
+	for _, e := range examples {
+		list = append(list, e)
+	}
+	slices.SortFunc(list, func(a, b Example) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return list
+}
+
+// Driver は指定されたドライバ名をサンプルが対応しているものに解決する
+// 空の場合はデフォルトで、"pq" は "postgres" と同じ扱い
+func (e Example) Driver(name string) (string, error) {
+	if name == "" {
+		return e.Drivers[0], nil
+	}
+	if strings.EqualFold(name, "pq") {
+		name = dbsetup.DriverPq
+	}
+	for _, d := range e.Drivers {
+		if strings.EqualFold(d, name) {
+			return d, nil
+		}
+	}
+	return "", fmt.Errorf("%s: unsupported driver:%s", e.Name, name)
+}
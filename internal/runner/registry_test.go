@@ -0,0 +1,112 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+)
+
+// isolate はテストの終了時に登録されたサンプルを元に戻す
+func isolate(t *testing.T) {
+	t.Helper()
+	prev := examples
+	examples = map[string]Example{}
+	t.Cleanup(func() { examples = prev })
+}
+
+func nop(context.Context, DBs) error {
+	return nil
+}
+
+func TestRegisterPanic(t *testing.T) {
+	isolate(t)
+	Register(Example{Name: "Ex01", Target: Pg, Run: nop})
+
+	tests := []struct {
+		name string
+		e    Example
+		want string
+	}{
+		{"duplicate", Example{Name: "EX01", Target: Pg, Run: nop}, "twice"},
+		{"nil Run", Example{Name: "Ex02", Target: Pg}, "nil Run"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			defer func() {
+				r := recover()
+				if s, _ := r.(string); !strings.Contains(s, tt.want) {
+					t.Errorf("recover = %v", r)
+				}
+			}()
+			Register(tt.e)
+		})
+	}
+	if _, err := Lookup("Ex02"); !errors.Is(err, ErrUnknown) {
+		t.Errorf("Ex02 is registered: %v", err)
+	}
+}
+
+func TestLookup(t *testing.T) {
+	isolate(t)
+	Register(Example{Name: "Ex01Pg01", Target: Pg, Run: nop})
+	Register(Example{Name: "Ex01MySQL01", Target: MySQL, Run: nop})
+
+	for _, name := range []string{"Ex01Pg01", "ex01pg01", "EX01PG01"} {
+		e, err := Lookup(name)
+		if err != nil || e.Name != "Ex01Pg01" {
+			t.Errorf("%s: %v %v", name, e.Name, err)
+		}
+	}
+	if _, err := Lookup("ex01pg02"); !errors.Is(err, ErrUnknown) {
+		t.Errorf("err = %v", err)
+	}
+
+	var names []string
+	for _, e := range Examples() {
+		names = append(names, e.Name)
+	}
+	if got := strings.Join(names, ","); got != "Ex01MySQL01,Ex01Pg01" {
+		t.Errorf("Examples = %s", got)
+	}
+}
+
+func TestDriver(t *testing.T) {
+	isolate(t)
+	Register(Example{Name: "Pg", Target: Pg, Drivers: []string{dbsetup.DriverPgx, dbsetup.DriverPq}, Run: nop})
+	Register(Example{Name: "MySQL", Target: MySQL, Run: nop})
+	Register(Example{Name: "Either", Target: Either, Run: nop})
+
+	tests := []struct {
+		example string
+		name    string
+		want    string
+		err     bool
+	}{
+		{"Pg", "", dbsetup.DriverPgx, false},
+		{"Pg", "pq", dbsetup.DriverPq, false},
+		{"Pg", "PQ", dbsetup.DriverPq, false},
+		{"Pg", "postgres", dbsetup.DriverPq, false},
+		{"Pg", "mysql", "", true},
+		{"MySQL", "", dbsetup.DriverMySQL, false},
+		{"MySQL", "pgx", "", true},
+		{"Either", "", dbsetup.DriverPgx, false},
+		{"Either", "MySQL", dbsetup.DriverMySQL, false},
+		{"Either", "sqlite", "", true},
+	}
+	for _, tt := range tests {
+		e, err := Lookup(tt.example)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := e.Driver(tt.name)
+		if got != tt.want || (err != nil) != tt.err {
+			t.Errorf("%s %q: %q %v", tt.example, tt.name, got, err)
+		}
+		if err != nil && !strings.Contains(err.Error(), "unsupported driver:"+tt.name) {
+			t.Errorf("%s %q: %v", tt.example, tt.name, err)
+		}
+	}
+}
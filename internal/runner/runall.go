@@ -0,0 +1,115 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+)
+
+// Outcome はサンプルの実行結果
+type Outcome string
+
+const (
+	Pass  Outcome = "PASS"
+	Fail  Outcome = "FAIL"
+	XFail Outcome = "XFAIL" // 想定通りの失敗
+	XPass Outcome = "XPASS" // 失敗が想定されているのに成功
+)
+
+// Result はRunAllでのサンプル1つ分の結果
+type Result struct {
+	Name     string
+	Driver   string
+	Duration time.Duration
+	Outcome  Outcome
+	Err      error
+}
+
+func (e Example) outcome(err error) Outcome {
+	switch {
+	case e.ExpectErr == nil && err == nil:
+		return Pass
+	case e.ExpectErr == nil:
+		return Fail
+	case errors.Is(err, e.ExpectErr):
+		return XFail
+	case err == nil:
+		return XPass
+	}
+	return Fail
+}
+
+// RunAll は登録された全てのサンプルを名前順に実行する
+// サンプル毎にセットアップし直すので、スキーマは毎回初期化される
+// driverNameに対応していないサンプルはデフォルトのドライバで実行する
+// driverNameが空の場合、Eitherのサンプルはデフォルトのドライバとmysqlの両方で実行する
+func RunAll(ctx context.Context, setup Setup, driverName string) []Result {
+	var results []Result
+	for _, e := range Examples() {
+		driver, err := e.Driver(driverName)
+		if err != nil {
+			driver = e.Drivers[0]
+		}
+		drivers := []string{driver}
+		if e.Target == Either && driverName == "" && driver != dbsetup.DriverMySQL {
+			drivers = append(drivers, dbsetup.DriverMySQL)
+		}
+		for _, driver := range drivers {
+			start := time.Now()
+			err = runOne(ctx, setup, e, driver)
+			results = append(results, Result{
+				Name:     e.Name,
+				Driver:   driver,
+				Duration: time.Since(start),
+				Outcome:  e.outcome(err),
+				Err:      err,
+			})
+		}
+	}
+	return results
+}
+
+func runOne(ctx context.Context, setup Setup, e Example, driverName string) error {
+	ctx, cancel := context.WithTimeout(ctx, setup.timeout())
+	defer cancel()
+
+	dbs, err := setup.Open(ctx, e, driverName)
+	if err != nil {
+		return err
+	}
+	defer setup.close(ctx, dbs)
+
+	return e.Run(ctx, dbs)
+}
+
+// Failed はFAILとXPASSの件数
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if r.Outcome == Fail || r.Outcome == XPass {
+			n++
+		}
+	}
+	return n
+}
+
+// Summary は結果を表形式で出力する
+func Summary(w io.Writer, results []Result) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tDRIVER\tDURATION\tOUTCOME\tERROR")
+	for _, r := range results {
+		errText := ""
+		if r.Err != nil {
+			errText = r.Err.Error()
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n",
+			r.Name, r.Driver, r.Duration.Round(time.Millisecond), r.Outcome, errText,
+		)
+	}
+	tw.Flush()
+}
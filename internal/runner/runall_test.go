@@ -0,0 +1,98 @@
+package runner
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+)
+
+var (
+	errExpected = errors.New("expected")
+	errOther    = errors.New("other")
+)
+
+func TestOutcome(t *testing.T) {
+	tests := []struct {
+		expect error
+		err    error
+		want   Outcome
+	}{
+		{nil, nil, Pass},
+		{nil, errOther, Fail},
+		{errExpected, errExpected, XFail},
+		{errExpected, fmt.Errorf("exec: %w", errExpected), XFail},
+		{errExpected, errOther, Fail}, // 想定と違うエラーで失敗
+		{errExpected, nil, XPass},     // 失敗が想定されているのに成功
+	}
+	for _, tt := range tests {
+		e := Example{Name: "Ex01", ExpectErr: tt.expect}
+		if got := e.outcome(tt.err); got != tt.want {
+			t.Errorf("expect=%v err=%v: %s", tt.expect, tt.err, got)
+		}
+	}
+}
+
+func TestRunAll(t *testing.T) {
+	isolate(t)
+	run := func(err error) Func {
+		return func(context.Context, DBs) error { return err }
+	}
+	Register(Example{Name: "Ex01Pass", Target: Pg, Run: run(nil)})
+	Register(Example{Name: "Ex02Fail", Target: Pg, Run: run(errOther)})
+	Register(Example{Name: "Ex03XFail", Target: Pg, ExpectErr: errExpected, Run: run(errExpected)})
+	Register(Example{Name: "Ex04Other", Target: Pg, ExpectErr: errExpected, Run: run(errOther)})
+	Register(Example{Name: "Ex05XPass", Target: Pg, ExpectErr: errExpected, Run: run(nil)})
+	Register(Example{Name: "Ex06Either", Target: Either, Run: run(nil)})
+
+	var opened []string
+	results := RunAll(context.Background(), fakeSetup(&opened), "")
+
+	var got []string
+	for _, r := range results {
+		got = append(got, fmt.Sprintf("%s/%s=%s", r.Name, r.Driver, r.Outcome))
+	}
+	want := []string{
+		"Ex01Pass/pgx=PASS",
+		"Ex02Fail/pgx=FAIL",
+		"Ex03XFail/pgx=XFAIL",
+		"Ex04Other/pgx=FAIL",
+		"Ex05XPass/pgx=XPASS",
+		"Ex06Either/pgx=PASS",
+		"Ex06Either/mysql=PASS", // ドライバの指定がない場合はmysqlでも実行する
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("\n got: %v\nwant: %v", got, want)
+	}
+	if n := Failed(results); n != 3 {
+		t.Errorf("Failed = %d", n)
+	}
+	if len(opened) != len(want) {
+		t.Errorf("opened = %v", opened)
+	}
+
+	// ドライバを指定した場合、対応していないサンプルはデフォルトのドライバで実行する
+	opened = nil
+	results = RunAll(context.Background(), fakeSetup(&opened), dbsetup.DriverMySQL)
+	if len(results) != 6 || results[0].Driver != dbsetup.DriverPgx || results[5].Driver != dbsetup.DriverMySQL {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestSummary(t *testing.T) {
+	var b strings.Builder
+	Summary(&b, []Result{
+		{Name: "Ex01Pass", Driver: "pgx", Duration: 1234 * time.Microsecond, Outcome: Pass},
+		{Name: "Ex02Fail", Driver: "mysql", Duration: 2 * time.Millisecond, Outcome: Fail, Err: errOther},
+	})
+	want := "NAME      DRIVER  DURATION  OUTCOME  ERROR\n" +
+		"Ex01Pass  pgx     1ms       PASS     \n" +
+		"Ex02Fail  mysql   2ms       FAIL     other\n"
+	if got := b.String(); got != want {
+		t.Errorf("\n got:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,225 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+)
+
+const DefaultTimeout = 10 * time.Second
+
+// Setup は各章のデータベースのセットアップ処理
+type Setup struct {
+	Pg      func(ctx context.Context, driverName string) (*sql.DB, error)
+	MySQL   func(ctx context.Context) (*sql.DB, error)
+	Timeout time.Duration // サンプル1つあたりのタイムアウト。0の場合はDefaultTimeout
+	// Close はサンプルの終了後のクローズ処理。nilの場合はDB.Close
+	// ctxはサンプルのタイムアウトやキャンセルとは切り離したもの
+	Close func(ctx context.Context, db *sql.DB) error
+}
+
+func (s Setup) timeout() time.Duration {
+	if s.Timeout <= 0 {
+		return DefaultTimeout
+	}
+	return s.Timeout
+}
+
+// Open はサンプルのTargetに応じてデータベースをセットアップする
+func (s Setup) Open(ctx context.Context, e Example, driverName string) (DBs, error) {
+	usePg := e.Target == Pg || e.Target == Both || (e.Target == Either && driverName != dbsetup.DriverMySQL)
+	useMySQL := e.Target == MySQL || e.Target == Both || (e.Target == Either && driverName == dbsetup.DriverMySQL)
+
+	dbs := DBs{Driver: driverName}
+	if usePg {
+		if s.Pg == nil {
+			return DBs{}, fmt.Errorf("%s: no setup for %s", e.Name, Pg)
+		}
+		db, err := s.Pg(ctx, driverName)
+		if err != nil {
+			return DBs{}, err
+		}
+		dbs.Pg = db
+	}
+	if useMySQL {
+		if s.MySQL == nil {
+			dbs.Close(ctx)
+			return DBs{}, fmt.Errorf("%s: no setup for %s", e.Name, MySQL)
+		}
+		db, err := s.MySQL(ctx)
+		if err != nil {
+			dbs.Close(ctx)
+			return DBs{}, err
+		}
+		dbs.MySQL = db
+	}
+	return dbs, nil
+}
+
+// close はサンプルの終了後にSetup.Closeでデータベースをクローズする
+func (s Setup) close(ctx context.Context, dbs DBs) {
+	if s.Close == nil {
+		dbs.Close(ctx)
+		return
+	}
+	ctx = context.WithoutCancel(ctx)
+	for _, db := range []*sql.DB{dbs.Pg, dbs.MySQL} {
+		if db == nil {
+			continue
+		}
+		if err := s.Close(ctx, db); err != nil {
+			slog.WarnContext(ctx, "Close", "err", err)
+		}
+	}
+}
+
+// Close は設定されているデータベースをクローズする
+func (dbs DBs) Close(ctx context.Context) {
+	for _, db := range []*sql.DB{dbs.Pg, dbs.MySQL} {
+		if db == nil {
+			continue
+		}
+		if err := db.Close(); err != nil {
+			slog.WarnContext(ctx, "Close", "err", err)
+		}
+	}
+}
+
+// Main はコマンドライン引数を解釈して実行する
+//
+//	list
+//	describe サンプル名
+//	run サンプル名 [ドライバ名]
+//	run-all [ドライバ名]
+//	matrix [サンプル名]
+//	サンプル名 [ドライバ名]（runの省略形）
+func Main(ctx context.Context, args []string, setup Setup) error {
+	if len(args) < 1 {
+		return errors.New("no name")
+	}
+	switch strings.ToLower(args[0]) {
+	case "list":
+		List(os.Stdout)
+		return nil
+	case "describe":
+		if len(args) < 2 {
+			return errors.New("no name")
+		}
+		e, err := Lookup(args[1])
+		if err != nil {
+			return err
+		}
+		Describe(os.Stdout, e)
+		return nil
+	case "run-all":
+		driverName := ""
+		if 2 <= len(args) {
+			driverName = args[1]
+		}
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+		results := RunAll(ctx, setup, driverName)
+		Summary(os.Stdout, results)
+		if n := Failed(results); n != 0 {
+			return fmt.Errorf("%d of %d failed", n, len(results))
+		}
+		return nil
+	case "matrix":
+		var targets []Example
+		if 2 <= len(args) {
+			e, err := Lookup(args[1])
+			if err != nil {
+				return err
+			}
+			targets = append(targets, e)
+		} else {
+			for _, e := range Examples() {
+				if (e.Target == Pg || e.Target == Either) && 2 <= len(e.Drivers) {
+					targets = append(targets, e)
+				}
+			}
+		}
+		slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+		for _, e := range targets {
+			fmt.Printf("=== %s\n", e.Name)
+			if len(e.Drivers) < 2 {
+				fmt.Printf("skipped: single driver (%s)\n", strings.Join(e.Drivers, ", "))
+				continue
+			}
+			runs, err := Matrix(ctx, setup, e)
+			if err != nil {
+				return err
+			}
+			for _, run := range runs[1:] {
+				if Diff(os.Stdout, runs[0], run) == 0 {
+					fmt.Println("no differences")
+				}
+			}
+		}
+		return nil
+	case "run":
+		args = args[1:]
+		if len(args) < 1 {
+			return errors.New("no name")
+		}
+	}
+	e, err := Lookup(args[0])
+	if err != nil {
+		return err
+	}
+	driverName := ""
+	if 2 <= len(args) {
+		driverName = args[1]
+	}
+	return Run(ctx, setup, e, driverName)
+}
+
+// Run はデータベースをセットアップしてサンプルを1つ実行する
+func Run(ctx context.Context, setup Setup, e Example, driverName string) error {
+	driverName, err := e.Driver(driverName)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, setup.timeout())
+	defer cancel()
+
+	// 起動を待つ間のログもサンプルと同じ形式で出力する
+	slog.SetDefault(slog.New(slog.NewJSONHandler(os.Stdout, nil)))
+
+	dbs, err := setup.Open(ctx, e, driverName)
+	if err != nil {
+		return err
+	}
+	defer setup.close(ctx, dbs)
+
+	return e.Run(ctx, dbs)
+}
+
+// List は登録されたサンプルの一覧を出力する
+func List(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, e := range Examples() {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", e.Name, e.Target, e.Description)
+	}
+	tw.Flush()
+}
+
+// Describe はサンプルの詳細を出力する
+func Describe(w io.Writer, e Example) {
+	fmt.Fprintf(w, "Name:        %s\n", e.Name)
+	fmt.Fprintf(w, "Target:      %s\n", e.Target)
+	fmt.Fprintf(w, "Drivers:     %s\n", strings.Join(e.Drivers, ", "))
+	fmt.Fprintf(w, "Description: %s\n", e.Description)
+	if e.ExpectErr != nil {
+		fmt.Fprintf(w, "ExpectErr:   %v\n", e.ExpectErr)
+	}
+}
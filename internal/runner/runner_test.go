@@ -0,0 +1,161 @@
+package runner
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/ystkg/db-examples/internal/dbsetup"
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+// captureStdout はfの実行中に標準出力へ書き込まれた内容を返す
+// Runはslogのデフォルトを標準出力に差し替えるので、テストの終了時に元に戻す
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	prevLog := slog.Default()
+	t.Cleanup(func() { slog.SetDefault(prevLog) })
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := os.Stdout
+	os.Stdout = w
+	out := make(chan string)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- string(b)
+	}()
+	defer func() {
+		os.Stdout = prev
+	}()
+	f()
+	w.Close()
+	return <-out
+}
+
+// fakeSetup はfakedbでセットアップし、呼び出されたドライバ名を記録する
+func fakeSetup(drivers *[]string) Setup {
+	return Setup{
+		Pg: func(ctx context.Context, driverName string) (*sql.DB, error) {
+			*drivers = append(*drivers, driverName)
+			return fakedb.Open(fakedb.NewScript()), nil
+		},
+		MySQL: func(ctx context.Context) (*sql.DB, error) {
+			*drivers = append(*drivers, dbsetup.DriverMySQL)
+			return fakedb.Open(fakedb.NewScript()), nil
+		},
+	}
+}
+
+func TestMainDispatch(t *testing.T) {
+	isolate(t)
+	var ran []string
+	Register(Example{
+		Name:        "Ex01Pg01",
+		Target:      Pg,
+		Description: "説明",
+		Drivers:     []string{dbsetup.DriverPgx, dbsetup.DriverPq},
+		Run: func(ctx context.Context, dbs DBs) error {
+			if dbs.Pg == nil || dbs.MySQL != nil {
+				return errors.New("unexpected DBs")
+			}
+			ran = append(ran, dbs.Driver)
+			return nil
+		},
+	})
+	var opened []string
+	setup := fakeSetup(&opened)
+	ctx := context.Background()
+
+	out := captureStdout(t, func() {
+		if err := Main(ctx, []string{"list"}, setup); err != nil {
+			t.Error(err)
+		}
+	})
+	if !strings.Contains(out, "Ex01Pg01") || !strings.Contains(out, "説明") {
+		t.Errorf("list:\n%s", out)
+	}
+
+	out = captureStdout(t, func() {
+		if err := Main(ctx, []string{"describe", "ex01pg01"}, setup); err != nil {
+			t.Error(err)
+		}
+	})
+	if !strings.Contains(out, "Drivers:     pgx, postgres") {
+		t.Errorf("describe:\n%s", out)
+	}
+
+	captureStdout(t, func() {
+		for _, args := range [][]string{
+			{"run", "ex01pg01"},
+			{"run", "Ex01Pg01", "pq"},
+			{"EX01PG01"},
+			{"ex01pg01", "postgres"},
+		} {
+			if err := Main(ctx, args, setup); err != nil {
+				t.Errorf("%v: %v", args, err)
+			}
+		}
+	})
+	want := "pgx,postgres,pgx,postgres"
+	if got := strings.Join(ran, ","); got != want {
+		t.Errorf("ran = %s", got)
+	}
+	if got := strings.Join(opened, ","); got != want {
+		t.Errorf("opened = %s", got)
+	}
+}
+
+func TestMainError(t *testing.T) {
+	isolate(t)
+	Register(Example{Name: "Ex01Pg01", Target: Pg, Run: nop})
+	var opened []string
+	setup := fakeSetup(&opened)
+
+	tests := []struct {
+		args []string
+		err  error
+	}{
+		{nil, nil},
+		{[]string{"describe"}, nil},
+		{[]string{"run"}, nil},
+		{[]string{"describe", "ex99"}, ErrUnknown},
+		{[]string{"ex99"}, ErrUnknown},
+		{[]string{"ex01pg01", "mysql"}, nil},
+	}
+	for _, tt := range tests {
+		err := Main(context.Background(), tt.args, setup)
+		if err == nil || (tt.err != nil && !errors.Is(err, tt.err)) {
+			t.Errorf("%v: %v", tt.args, err)
+		}
+	}
+	if len(opened) != 0 {
+		t.Errorf("opened = %v", opened)
+	}
+}
+
+func TestMainMatrixSingleDriver(t *testing.T) {
+	isolate(t)
+	Register(Example{Name: "Ex01Pg01", Target: Pg, Run: nop})
+	var opened []string
+
+	out := captureStdout(t, func() {
+		if err := Main(context.Background(), []string{"matrix", "ex01pg01"}, fakeSetup(&opened)); err != nil {
+			t.Error(err)
+		}
+	})
+	// ドライバが1つしかない場合は実行せずにその旨を出力する
+	if want := "=== Ex01Pg01\nskipped: single driver (pgx)\n"; out != want {
+		t.Errorf("\n got: %q\nwant: %q", out, want)
+	}
+	if len(opened) != 0 {
+		t.Errorf("opened = %v", opened)
+	}
+}
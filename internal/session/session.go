@@ -0,0 +1,191 @@
+// Package session はクライアント側のコネクションとサーバ側のセッションを対応付ける
+//
+// sqltraceでドライバのConnectorをラップし、接続した時点でpg_backend_pid()かCONNECTION_ID()を記録する
+// サーバ側の状態はpg_stat_activityかinformation_schema.PROCESSLISTを監視用の*sql.DBで確認する
+// 監視用の*sql.DBを分けるのは、確認のためのクエリで観測対象のプールの状態を変えないため
+package session
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/sqltrace"
+)
+
+// Session はサーバ側のセッション1つ分
+type Session struct {
+	ConnID int64  // sqltraceの物理コネクションの番号
+	ID     int64  // pg_backend_pid()かCONNECTION_ID()
+	State  string // PostgreSQLはpg_stat_activityのstate、MySQLはPROCESSLISTのCOMMAND。見つからない場合は空
+	InTx   bool   // トランザクション中かどうか
+	Query  string // PostgreSQLは最後に実行したSQL、MySQLは実行中のSQL
+}
+
+// BackendID はconnのサーバ側のセッションのIDを返す
+func BackendID(ctx context.Context, conn *sql.Conn, d dialect.Dialect) (int64, error) {
+	var id int64
+	err := conn.QueryRowContext(ctx, idQuery(d)).Scan(&id)
+	return id, err
+}
+
+func idQuery(d dialect.Dialect) string {
+	if d == dialect.MySQL {
+		return "SELECT CONNECTION_ID()"
+	}
+	return "SELECT pg_backend_pid()"
+}
+
+// Observer は物理コネクション毎のサーバ側のセッションのIDを記録する
+type Observer struct {
+	dialect dialect.Dialect
+	monitor *sql.DB
+
+	mu  sync.Mutex
+	ids map[int64]int64 // sqltraceの番号からセッションのID
+}
+
+// New はmonitorでサーバ側の状態を確認するObserverを返す
+func New(d dialect.Dialect, monitor *sql.DB) *Observer {
+	return &Observer{dialect: d, monitor: monitor, ids: map[int64]int64{}}
+}
+
+// Wrap はConnectorをラップする。DB.Closeの時点で監視用の*sql.DBもクローズする
+func (o *Observer) Wrap(c driver.Connector) driver.Connector {
+	return &connector{Connector: sqltrace.Wrap(c, o.Observe), observer: o}
+}
+
+type connector struct {
+	driver.Connector
+	observer *Observer
+}
+
+func (c *connector) Close() error {
+	err := c.observer.monitor.Close()
+	if closer, ok := c.Connector.(io.Closer); ok {
+		if errClose := closer.Close(); err == nil {
+			err = errClose
+		}
+	}
+	return err
+}
+
+// Observe はsqltraceのObserver。接続した時点でセッションのIDを取得する
+func (o *Observer) Observe(ev sqltrace.Event) {
+	switch ev.Op {
+	case sqltrace.OpConnect:
+		if ev.Err != nil {
+			return
+		}
+		id, err := o.backendID(ev.Conn)
+		if err != nil {
+			return
+		}
+		o.mu.Lock()
+		o.ids[ev.ConnID] = id
+		o.mu.Unlock()
+	case sqltrace.OpClose:
+		o.mu.Lock()
+		delete(o.ids, ev.ConnID)
+		o.mu.Unlock()
+	}
+}
+
+// backendID はドライバのコネクションで直接IDを取得する（database/sqlを経由しないのでプールの状態は変わらない）
+func (o *Observer) backendID(c driver.Conn) (int64, error) {
+	q, ok := c.(driver.QueryerContext)
+	if !ok {
+		return 0, fmt.Errorf("session: %T does not implement driver.QueryerContext", c)
+	}
+	rows, err := q.QueryContext(context.Background(), idQuery(o.dialect), nil)
+	if err != nil {
+		return 0, err
+	}
+	defer rows.Close()
+	dest := make([]driver.Value, 1)
+	if err := rows.Next(dest); err != nil {
+		return 0, err
+	}
+	switch v := dest[0].(type) {
+	case int64:
+		return v, nil
+	case int32:
+		return int64(v), nil
+	case uint64:
+		return int64(v), nil
+	case []byte:
+		var id int64
+		_, err := fmt.Sscan(string(v), &id)
+		return id, err
+	}
+	return 0, fmt.Errorf("session: unexpected id type:%T", dest[0])
+}
+
+// ID はsqltraceの番号に対応するセッションのID
+func (o *Observer) ID(connID int64) (int64, bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	id, ok := o.ids[connID]
+	return id, ok
+}
+
+// Sessions は記録している全てのセッションのサーバ側の状態をConnIDの順に返す
+func (o *Observer) Sessions(ctx context.Context) ([]Session, error) {
+	o.mu.Lock()
+	sessions := make([]Session, 0, len(o.ids))
+	for connID, id := range o.ids {
+		sessions = append(sessions, Session{ConnID: connID, ID: id})
+	}
+	o.mu.Unlock()
+	slices.SortFunc(sessions, func(a, b Session) int {
+		return cmp.Compare(a.ConnID, b.ConnID)
+	})
+	if len(sessions) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, len(sessions))
+	for i, s := range sessions {
+		args[i] = s.ID
+	}
+	rows, err := o.monitor.QueryContext(ctx, o.activityQuery(len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var state, query string
+		var inTx bool
+		if err := rows.Scan(&id, &state, &inTx, &query); err != nil {
+			return nil, err
+		}
+		for i := range sessions {
+			if sessions[i].ID == id {
+				sessions[i].State, sessions[i].InTx, sessions[i].Query = state, inTx, query
+			}
+		}
+	}
+	return sessions, rows.Err()
+}
+
+// activityQuery はn個のセッションの状態を取得するSQL
+// 列はID、状態、トランザクション中かどうか、SQL
+func (o *Observer) activityQuery(n int) string {
+	if o.dialect == dialect.MySQL {
+		// PROCESSLISTにはトランザクションの状態がないのでINNODB_TRXで確認する
+		return "SELECT p.ID, p.COMMAND, t.trx_id IS NOT NULL, COALESCE(p.INFO, '')" +
+			" FROM information_schema.PROCESSLIST p" +
+			" LEFT JOIN information_schema.INNODB_TRX t ON t.trx_mysql_thread_id = p.ID" +
+			" WHERE p.ID IN " + o.dialect.In(1, n)
+	}
+	return "SELECT pid, COALESCE(state, ''), COALESCE(xact_start IS NOT NULL, false), COALESCE(query, '')" +
+		" FROM pg_stat_activity" +
+		" WHERE pid IN " + o.dialect.In(1, n)
+}
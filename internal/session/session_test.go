@@ -0,0 +1,95 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ystkg/db-examples/internal/dialect"
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+func TestSessions(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("SELECT pg_backend_pid()").Rows([]string{"pg_backend_pid"}, []driver.Value{int64(101)}).Once()
+	s.Query("SELECT pg_backend_pid()").Rows([]string{"pg_backend_pid"}, []driver.Value{int64(102)})
+	monitor := fakedb.NewScript()
+	monitor.Query("FROM pg_stat_activity").Rows([]string{"pid", "state", "in_tx", "query"},
+		[]driver.Value{int64(102), "idle", false, "SELECT 1"},
+		[]driver.Value{int64(101), "idle in transaction", true, "UPDATE shop SET name = $1"},
+	)
+	o := New(dialect.Postgres, fakedb.Open(monitor))
+	db := sql.OpenDB(o.Wrap(fakedb.NewConnector(s)))
+	ctx := context.Background()
+
+	conn1, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn1.Close()
+	conn2, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn2.Close()
+	if id, ok := o.ID(2); !ok || id != 102 {
+		t.Errorf("ID(2) = %d, %t", id, ok)
+	}
+
+	sessions, err := o.Sessions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Session{
+		{ConnID: 1, ID: 101, State: "idle in transaction", InTx: true, Query: "UPDATE shop SET name = $1"},
+		{ConnID: 2, ID: 102, State: "idle", Query: "SELECT 1"},
+	}
+	if !slices.Equal(sessions, want) {
+		t.Errorf("sessions\n got: %+v\nwant: %+v", sessions, want)
+	}
+	call := monitor.Calls()[1]
+	if !strings.HasSuffix(call.Query, "WHERE pid IN ($1,$2)") || !slices.Equal(call.Args, []any{int64(101), int64(102)}) {
+		t.Errorf("call = %s %v", call.Query, call.Args)
+	}
+
+	// 物理的にクローズされたコネクションは対象外になる
+	conn2.Raw(func(any) error { return driver.ErrBadConn })
+	if _, ok := o.ID(2); ok {
+		t.Error("ID(2) remains after close")
+	}
+}
+
+func TestMySQL(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("SELECT CONNECTION_ID()").Rows([]string{"CONNECTION_ID()"}, []driver.Value{[]byte("8")})
+	monitor := fakedb.NewScript()
+	monitor.Query("information_schema.PROCESSLIST").Rows([]string{"ID", "COMMAND", "in_tx", "INFO"},
+		[]driver.Value{int64(8), "Sleep", int64(1), ""},
+	)
+	o := New(dialect.MySQL, fakedb.Open(monitor))
+	db := sql.OpenDB(o.Wrap(fakedb.NewConnector(s)))
+	ctx := context.Background()
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	sessions, err := o.Sessions(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []Session{{ConnID: 1, ID: 8, State: "Sleep", InTx: true}}; !slices.Equal(sessions, want) {
+		t.Errorf("sessions = %+v", sessions)
+	}
+	if q := monitor.Calls()[1].Query; !strings.HasSuffix(q, "WHERE p.ID IN (?)") {
+		t.Errorf("query = %s", q)
+	}
+
+	// DB.Closeで監視用の*sql.DBもクローズする
+	db.Close()
+	if err := o.monitor.PingContext(ctx); err == nil {
+		t.Error("monitor is not closed")
+	}
+}
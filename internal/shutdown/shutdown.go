@@ -0,0 +1,130 @@
+// Package shutdown はコネクションプールを使用中のコネクションの返却を待ってからクローズする
+//
+// DB.CloseはIdleのコネクションだけをすぐにクローズし、InUseのコネクションは返却された時点でクローズする
+// Drainerは新しい接続を拒否してから、InUseのコネクションが返却されるのを期限まで待ち、
+// 残っているものを報告してからDB.Closeを呼び出す
+//
+// 新しい処理を止めるのは新しい接続の拒否とIdleを保持しないことによる間接的なもので、
+// プールの空きを待っている呼び出しには返却されたコネクションが渡される。
+// 確実に止めるには、呼び出し側でキャンセルしてから（ex02ではシグナルでサンプルのコンテキストをキャンセルする）Shutdownを呼び出す
+package shutdown
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/leak"
+)
+
+// ErrDraining は終了処理中のため接続を拒否したことを表す
+var ErrDraining = errors.New("shutdown: draining")
+
+// DefaultPoll はInUseを確認する間隔のデフォルト
+const DefaultPoll = 10 * time.Millisecond
+
+// Drainer は終了処理の状態
+type Drainer struct {
+	Leaks *leak.Detector // 指定した場合は返却されなかったもののスタックトレースをReportに含める
+	Poll  time.Duration  // InUseを確認する間隔。0の場合はDefaultPoll
+
+	draining atomic.Bool
+}
+
+// New はDrainerを返す
+func New() *Drainer {
+	return &Drainer{}
+}
+
+// Wrap はConnectorをラップする。Stopの後の接続はErrDrainingになる
+func (d *Drainer) Wrap(c driver.Connector) driver.Connector {
+	return &connector{Connector: c, drainer: d}
+}
+
+type connector struct {
+	driver.Connector
+	drainer *Drainer
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	if c.drainer.Draining() {
+		return nil, ErrDraining
+	}
+	return c.Connector.Connect(ctx)
+}
+
+func (c *connector) Close() error {
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Stop は新しい接続を拒否する
+//
+// 拒否するのは物理的な接続だけで、プールからの取り出しは止めない
+// MaxOpenConnsに達して待っている呼び出しには、返却されたコネクションがそのまま渡されて処理が続く
+func (d *Drainer) Stop() {
+	d.draining.Store(true)
+}
+
+// Draining はStopの後かどうか
+func (d *Drainer) Draining() bool {
+	return d.draining.Load()
+}
+
+// Report は終了処理の結果
+type Report struct {
+	InUse       int           // 開始した時点のInUse
+	Outstanding int           // 期限までに返却されなかったInUse
+	Waited      time.Duration // 返却を待った時間
+	Leaks       []leak.Leak   // Leaksを指定した場合のみ
+}
+
+// Shutdown はdbの終了処理。ctxの期限までInUseのコネクションの返却を待つ
+//
+//  1. Stopで新しい接続を拒否し、Idleのコネクションを保持しないようにする
+//     新しい処理は接続が必要な場合にErrDrainingになる。ただし、MaxOpenConnsに達して
+//     プールの空きを待っている呼び出しには返却されたコネクションが渡されるので、その処理は続く
+//  2. 返却されたコネクションはその時点でクローズされる。InUseが0になるかctxの期限まで待つ
+//  3. DB.Closeを呼び出す。返却されなかったコネクションは返却された時点でクローズされる
+func (d *Drainer) Shutdown(ctx context.Context, db *sql.DB) (Report, error) {
+	d.Stop()
+	db.SetMaxIdleConns(-1)
+
+	start := time.Now()
+	r := Report{InUse: db.Stats().InUse}
+	slog.InfoContext(ctx, "drain", "InUse", r.InUse)
+
+	poll := d.Poll
+	if poll <= 0 {
+		poll = DefaultPoll
+	}
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+wait:
+	for 0 < db.Stats().InUse {
+		select {
+		case <-ctx.Done():
+			break wait
+		case <-ticker.C:
+		}
+	}
+	r.Waited = time.Since(start)
+	r.Outstanding = db.Stats().InUse
+
+	if r.Outstanding == 0 {
+		slog.InfoContext(ctx, "drained", "waited", r.Waited)
+	} else {
+		if d.Leaks != nil {
+			r.Leaks = d.Leaks.Leaks()
+		}
+		slog.WarnContext(ctx, "outstanding", "InUse", r.Outstanding, "waited", r.Waited)
+	}
+	return r, db.Close()
+}
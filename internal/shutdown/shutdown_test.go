@@ -0,0 +1,115 @@
+package shutdown
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+	"github.com/ystkg/db-examples/internal/leak"
+)
+
+func TestShutdown(t *testing.T) {
+	s := fakedb.NewScript()
+	d := New()
+	db := sql.OpenDB(d.Wrap(fakedb.NewConnector(s)))
+	ctx := context.Background()
+
+	if err := db.PingContext(ctx); err != nil {
+		t.Fatal(err)
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.AfterFunc(20*time.Millisecond, func() { conn.Close() })
+
+	deadline, cancel := context.WithTimeout(ctx, time.Second)
+	defer cancel()
+	r, err := d.Shutdown(deadline, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.InUse != 1 || r.Outstanding != 0 || r.Waited < 20*time.Millisecond || r.Leaks != nil {
+		t.Errorf("report = %+v", r)
+	}
+	// 返却されたコネクションはクローズされている
+	closed := 0
+	for _, c := range s.Calls() {
+		if c.Op == fakedb.OpClose {
+			closed++
+		}
+	}
+	if closed != 1 {
+		t.Errorf("Close = %d", closed)
+	}
+}
+
+func TestShutdownOutstanding(t *testing.T) {
+	detector := leak.New(0)
+	d := New()
+	d.Leaks = detector
+	db := sql.OpenDB(detector.Wrap(d.Wrap(fakedb.NewConnector(fakedb.NewScript()))))
+	ctx := context.Background()
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+
+	deadline, cancel := context.WithTimeout(ctx, 20*time.Millisecond)
+	defer cancel()
+	r, err := d.Shutdown(deadline, db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.InUse != 1 || r.Outstanding != 1 || len(r.Leaks) != 2 || r.Leaks[1].Kind != leak.Tx {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+func TestDraining(t *testing.T) {
+	d := New()
+	db := sql.OpenDB(d.Wrap(fakedb.NewConnector(fakedb.NewScript())))
+	defer db.Close()
+	ctx := context.Background()
+
+	d.Stop()
+	if err := db.PingContext(ctx); !errors.Is(err, ErrDraining) {
+		t.Errorf("err = %v", err)
+	}
+}
+
+// Stopの後でも、空きを待っている呼び出しには返却されたコネクションが渡される
+func TestDrainingWaiter(t *testing.T) {
+	d := New()
+	db := sql.OpenDB(d.Wrap(fakedb.NewConnector(fakedb.NewScript())))
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := make(chan error, 1)
+	go func() {
+		waiter, err := db.Conn(ctx)
+		if err == nil {
+			waiter.Close()
+		}
+		got <- err
+	}()
+	for db.Stats().WaitCount == 0 {
+		time.Sleep(time.Millisecond)
+	}
+
+	d.Stop()
+	conn.Close()
+	if err := <-got; err != nil {
+		t.Errorf("err = %v", err)
+	}
+}
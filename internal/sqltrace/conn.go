@@ -0,0 +1,173 @@
+package sqltrace
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"time"
+)
+
+type conn struct {
+	driver.Conn
+	id  int64
+	obs Observer
+}
+
+var (
+	_ driver.Conn               = (*conn)(nil)
+	_ driver.ConnPrepareContext = (*conn)(nil)
+	_ driver.ConnBeginTx        = (*conn)(nil)
+	_ driver.ExecerContext      = (*conn)(nil)
+	_ driver.QueryerContext     = (*conn)(nil)
+	_ driver.Pinger             = (*conn)(nil)
+	_ driver.SessionResetter    = (*conn)(nil)
+	_ driver.Validator          = (*conn)(nil)
+	_ driver.NamedValueChecker  = (*conn)(nil)
+)
+
+func (c *conn) emit(ev Event) {
+	ev.ConnID = c.id
+	ev.Conn = c.Conn
+	c.obs(ev)
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	return c.PrepareContext(context.Background(), query)
+}
+
+func (c *conn) PrepareContext(ctx context.Context, query string) (driver.Stmt, error) {
+	start := time.Now()
+	var s driver.Stmt
+	var err error
+	if p, ok := c.Conn.(driver.ConnPrepareContext); ok {
+		s, err = p.PrepareContext(ctx, query)
+	} else {
+		s, err = c.Conn.Prepare(query)
+	}
+	c.emit(Event{Op: OpPrepare, Query: query, Err: err, Duration: time.Since(start)})
+	if err != nil {
+		return nil, err
+	}
+	return &stmt{Stmt: s, conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	start := time.Now()
+	err := c.Conn.Close()
+	c.emit(Event{Op: OpClose, Err: err, Duration: time.Since(start)})
+	return err
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	start := time.Now()
+	var t driver.Tx
+	var err error
+	if b, ok := c.Conn.(driver.ConnBeginTx); ok {
+		t, err = b.BeginTx(ctx, opts)
+	} else if opts.Isolation != 0 || opts.ReadOnly {
+		err = errors.New("sqltrace: driver does not support non-default transaction options")
+	} else {
+		t, err = c.Conn.Begin() // BeginTxに対応していないドライバ向け
+	}
+	c.emit(Event{Op: OpBegin, Err: err, Duration: time.Since(start)})
+	if err != nil {
+		return nil, err
+	}
+	return &tx{Tx: t, conn: c}, nil
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, ok := c.Conn.(driver.ExecerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	c.emit(Event{Op: OpStart, Query: query, Args: len(args)})
+	start := time.Now()
+	res, err := e.ExecContext(ctx, query, args)
+	if errors.Is(err, driver.ErrSkip) {
+		return nil, err
+	}
+	c.emit(Event{Op: OpExec, Query: query, Args: len(args), Result: res, Err: err, Duration: time.Since(start)})
+	return res, err
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	q, ok := c.Conn.(driver.QueryerContext)
+	if !ok {
+		return nil, driver.ErrSkip
+	}
+	c.emit(Event{Op: OpStart, Query: query, Args: len(args)})
+	start := time.Now()
+	r, err := q.QueryContext(ctx, query, args)
+	if errors.Is(err, driver.ErrSkip) {
+		return nil, err
+	}
+	c.emit(Event{Op: OpQuery, Query: query, Args: len(args), Err: err, Duration: time.Since(start)})
+	if err != nil {
+		return nil, err
+	}
+	return &rows{Rows: r, conn: c, query: query, start: start}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if p, ok := c.Conn.(driver.Pinger); ok {
+		return p.Ping(ctx)
+	}
+	return nil
+}
+
+// ResetSession はdatabase/sqlが使用済みのコネクションをプールから取り出す時に呼ばれる
+func (c *conn) ResetSession(ctx context.Context) error {
+	start := time.Now()
+	var err error
+	if r, ok := c.Conn.(driver.SessionResetter); ok {
+		err = r.ResetSession(ctx)
+	}
+	c.emit(Event{Op: OpResetSession, Err: err, Duration: time.Since(start)})
+	return err
+}
+
+// IsValid はdatabase/sqlがコネクションをプールに返却する時に呼ばれる
+func (c *conn) IsValid() bool {
+	valid := true
+	if v, ok := c.Conn.(driver.Validator); ok {
+		valid = v.IsValid()
+	}
+	c.emit(Event{Op: OpCheckin, Valid: valid})
+	return valid
+}
+
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if n, ok := c.Conn.(driver.NamedValueChecker); ok {
+		return n.CheckNamedValue(nv)
+	}
+	return driver.ErrSkip
+}
+
+type tx struct {
+	driver.Tx
+	conn *conn
+}
+
+func (t *tx) Commit() error {
+	start := time.Now()
+	err := t.Tx.Commit()
+	t.conn.emit(Event{Op: OpCommit, Err: err, Duration: time.Since(start)})
+	return err
+}
+
+func (t *tx) Rollback() error {
+	start := time.Now()
+	err := t.Tx.Rollback()
+	t.conn.emit(Event{Op: OpRollback, Err: err, Duration: time.Since(start)})
+	return err
+}
+
+// Unwrap はラップ元のコネクション。sql.Conn.Rawでドライバ固有の機能を使うために使う
+func (c *conn) Unwrap() driver.Conn {
+	return c.Conn
+}
@@ -0,0 +1,80 @@
+// Package sqltrace はdatabase/sqlのドライバをラップして呼び出しを観測する
+package sqltrace
+
+import (
+	"context"
+	"database/sql/driver"
+	"io"
+	"sync/atomic"
+	"time"
+)
+
+// Op は観測対象の操作
+type Op string
+
+const (
+	OpConnect      Op = "connect"
+	OpClose        Op = "close"
+	OpPrepare      Op = "prepare"
+	OpStmtClose    Op = "stmt-close"
+	OpStart        Op = "start" // ExecとQueryの開始。ドライバがErrSkipを返してプリペアドステートメントで実行し直す場合は2回通知される
+	OpExec         Op = "exec"
+	OpQuery        Op = "query"
+	OpRowsClose    Op = "rows-close"
+	OpBegin        Op = "begin"
+	OpCommit       Op = "commit"
+	OpRollback     Op = "rollback"
+	OpResetSession Op = "reset-session" // 使用済みのコネクションをプールから取り出した（ResetSession）
+	OpCheckin      Op = "checkin"       // コネクションをプールに返却した（IsValid）
+)
+
+// Event は観測した操作1回分
+type Event struct {
+	Op       Op
+	ConnID   int64 // 物理コネクション毎の連番
+	Query    string
+	Args     int
+	Rows     int64         // OpRowsCloseまでに読み込んだ行数
+	Result   driver.Result // OpExecの結果
+	Valid    bool          // OpCheckinでコネクションが再利用可能と判定されたかどうか
+	Err      error
+	Duration time.Duration
+	Conn     driver.Conn // ラップ元のコネクション。Observerの呼び出し中のみ有効
+}
+
+// Observer はEventを受け取る。ドライバの呼び出しと同じゴルーチンで同期的に呼ばれる
+type Observer func(ev Event)
+
+// Wrap はConnectorをラップし、Observerに通知するConnectorを返す
+func Wrap(c driver.Connector, obs Observer) driver.Connector {
+	return &connector{Connector: c, obs: obs}
+}
+
+type connector struct {
+	driver.Connector
+	obs    Observer
+	nextID atomic.Int64
+}
+
+func (c *connector) Connect(ctx context.Context) (driver.Conn, error) {
+	start := time.Now()
+	dc, err := c.Connector.Connect(ctx)
+	id := c.nextID.Add(1)
+	c.obs(Event{Op: OpConnect, ConnID: id, Err: err, Duration: time.Since(start), Conn: dc})
+	if err != nil {
+		return nil, err
+	}
+	return &conn{Conn: dc, id: id, obs: c.obs}, nil
+}
+
+func (c *connector) Driver() driver.Driver {
+	return c.Connector.Driver()
+}
+
+// Close はdatabase/sqlがDB.Closeで呼ぶ。ラップ元がio.Closerの場合はCloseする
+func (c *connector) Close() error {
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
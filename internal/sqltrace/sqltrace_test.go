@@ -0,0 +1,85 @@
+package sqltrace
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"slices"
+	"sync"
+	"testing"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+type events struct {
+	mu    sync.Mutex
+	lines []string
+}
+
+func (e *events) observe(ev Event) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.lines = append(e.lines, fmt.Sprintf("%d %s", ev.ConnID, ev.Op))
+}
+
+func TestPoolEvents(t *testing.T) {
+	s := fakedb.NewScript()
+	s.Query("SELECT").Rows([]string{"n"}, []driver.Value{int64(1)}, []driver.Value{int64(2)})
+	e := &events{}
+	db := sql.OpenDB(Wrap(fakedb.NewConnector(s), e.observe))
+	ctx := context.Background()
+
+	if _, err := db.ExecContext(ctx, "INSERT"); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.QueryContext(ctx, "SELECT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for rows.Next() {
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	// 2回目以降はプールから取り出す時にreset-session、返却する時にcheckinが通知される
+	want := []string{
+		"1 connect", "1 start", "1 exec", "1 checkin",
+		"1 reset-session", "1 start", "1 query", "1 rows-close", "1 checkin",
+		"1 reset-session", "1 begin", "1 rollback", "1 checkin",
+		"1 close",
+	}
+	if !slices.Equal(e.lines, want) {
+		t.Errorf("events\n got: %q\nwant: %q", e.lines, want)
+	}
+}
+
+func TestUnwrap(t *testing.T) {
+	db := sql.OpenDB(Wrap(fakedb.NewConnector(fakedb.NewScript()), func(Event) {}))
+	defer db.Close()
+	c, err := db.Conn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer c.Close()
+
+	err = c.Raw(func(driverConn any) error {
+		u, ok := driverConn.(interface{ Unwrap() driver.Conn })
+		if !ok {
+			return fmt.Errorf("%T does not implement Unwrap", driverConn)
+		}
+		if _, ok := u.Unwrap().(*conn); ok {
+			return fmt.Errorf("Unwrap returned the wrapper")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Error(err)
+	}
+}
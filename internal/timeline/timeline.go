@@ -0,0 +1,222 @@
+// Package timeline はコネクションプールの状態（sql.DBStats）の推移を記録する
+//
+// 一定間隔のサンプリングに加えて、sqltraceのイベント毎にDB.Statsを記録する
+// 記録した推移はASCIIのチャートかJSONで出力する
+package timeline
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/sqltrace"
+)
+
+// サンプリングによる記録のイベント名
+const (
+	EventInit  = "init"  // Startの時点
+	EventTick  = "tick"  // 一定間隔のサンプリング
+	EventFinal = "final" // Stopの時点
+)
+
+// Sample は記録1回分
+type Sample struct {
+	Elapsed time.Duration // Startからの経過時間
+	Event   string        // sqltraceのOpかEventInit、EventTick、EventFinal
+	ConnID  int64         // sqltraceのイベントのみ
+	Stats   sql.DBStats
+}
+
+// Recorder はDB.Statsの推移を記録する
+type Recorder struct {
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	db      *sql.DB
+	start   time.Time
+	samples []Sample
+	stopped bool
+	stop    chan struct{}
+	done    chan struct{}
+}
+
+// New はintervalの間隔でサンプリングするRecorderを返す。0以下の場合はイベント毎の記録だけになる
+func New(interval time.Duration) *Recorder {
+	return &Recorder{interval: interval, now: time.Now}
+}
+
+// Wrap はConnectorをラップする。イベント毎に記録し、DB.Closeの時点でStopする
+func (r *Recorder) Wrap(c driver.Connector) driver.Connector {
+	return &connector{Connector: sqltrace.Wrap(c, r.Observe), recorder: r}
+}
+
+type connector struct {
+	driver.Connector
+	recorder *Recorder
+}
+
+func (c *connector) Close() error {
+	c.recorder.Stop()
+	if closer, ok := c.Connector.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// Start はdbの記録を開始する。Start以前のイベントは記録しない
+func (r *Recorder) Start(db *sql.DB) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.db != nil {
+		return
+	}
+	r.db = db
+	r.start = r.now()
+	r.samples = append(r.samples, Sample{Event: EventInit, Stats: db.Stats()})
+	if 0 < r.interval {
+		r.stop = make(chan struct{})
+		r.done = make(chan struct{})
+		go r.tick(r.stop, r.done)
+	}
+}
+
+func (r *Recorder) tick(stop <-chan struct{}, done chan<- struct{}) {
+	defer close(done)
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			r.record(EventTick, 0)
+		}
+	}
+}
+
+// Stop はサンプリングを止めて最後の状態を記録する。以降は記録しない
+func (r *Recorder) Stop() {
+	r.mu.Lock()
+	if r.db == nil || r.stopped {
+		r.mu.Unlock()
+		return
+	}
+	r.stopped = true
+	stop, done := r.stop, r.done
+	r.mu.Unlock()
+
+	if stop != nil {
+		close(stop)
+		<-done
+	}
+	r.record(EventFinal, 0)
+}
+
+// Observe はsqltraceのObserver。他のObserverと組み合わせる場合に直接使う
+// 記録するのはドライバが呼ばれた時点の状態で、database/sqlによるInUseやIdleの更新の前になる
+func (r *Recorder) Observe(ev sqltrace.Event) {
+	if ev.Op == sqltrace.OpStart {
+		return
+	}
+	r.record(string(ev.Op), ev.ConnID)
+}
+
+func (r *Recorder) record(event string, connID int64) {
+	r.mu.Lock()
+	db := r.db
+	skip := db == nil || (r.stopped && event != EventFinal)
+	r.mu.Unlock()
+	if skip {
+		return
+	}
+	stats := db.Stats()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.samples = append(r.samples, Sample{Elapsed: r.now().Sub(r.start), Event: event, ConnID: connID, Stats: stats})
+}
+
+// Samples は記録した推移
+func (r *Recorder) Samples() []Sample {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Sample(nil), r.samples...)
+}
+
+// WriteChart は推移をASCIIのチャートで出力する
+// poolの列は#がInUse、-がIdleのコネクション数で、幅はMaxOpenConnections（無制限の場合はOpenの最大値）
+func WriteChart(w io.Writer, samples []Sample) error {
+	width := 1
+	for _, s := range samples {
+		width = max(width, s.Stats.MaxOpenConnections, s.Stats.OpenConnections)
+	}
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "elapsed\tevent\tconn\tpool\tOpen\tInUse\tIdle\tWaitCount\tWaitDuration\tMaxIdleClosed\tMaxIdleTimeClosed\tMaxLifetimeClosed")
+	for _, s := range samples {
+		conn := ""
+		if s.ConnID != 0 {
+			conn = fmt.Sprint(s.ConnID)
+		}
+		st := s.Stats
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%s\t%d\t%d\t%d\n",
+			s.Elapsed.Round(time.Microsecond), s.Event, conn, bar(st, width),
+			st.OpenConnections, st.InUse, st.Idle, st.WaitCount, st.WaitDuration.Round(time.Microsecond),
+			st.MaxIdleClosed, st.MaxIdleTimeClosed, st.MaxLifetimeClosed,
+		)
+	}
+	return tw.Flush()
+}
+
+func bar(st sql.DBStats, width int) string {
+	inUse := min(st.InUse, width)
+	idle := min(st.Idle, width-inUse)
+	return "[" + strings.Repeat("#", inUse) + strings.Repeat("-", idle) + strings.Repeat(" ", width-inUse-idle) + "]"
+}
+
+// jsonSample はWriteJSONの1要素。時間はナノ秒
+type jsonSample struct {
+	Elapsed           int64  `json:"elapsed"`
+	Event             string `json:"event"`
+	ConnID            int64  `json:"conn,omitempty"`
+	MaxOpen           int    `json:"maxOpen"`
+	Open              int    `json:"open"`
+	InUse             int    `json:"inUse"`
+	Idle              int    `json:"idle"`
+	WaitCount         int64  `json:"waitCount"`
+	WaitDuration      int64  `json:"waitDuration"`
+	MaxIdleClosed     int64  `json:"maxIdleClosed"`
+	MaxIdleTimeClosed int64  `json:"maxIdleTimeClosed"`
+	MaxLifetimeClosed int64  `json:"maxLifetimeClosed"`
+}
+
+// WriteJSON は推移をグラフの描画用にJSONの配列で出力する。時間はナノ秒
+func WriteJSON(w io.Writer, samples []Sample) error {
+	list := make([]jsonSample, len(samples))
+	for i, s := range samples {
+		st := s.Stats
+		list[i] = jsonSample{
+			Elapsed:           s.Elapsed.Nanoseconds(),
+			Event:             s.Event,
+			ConnID:            s.ConnID,
+			MaxOpen:           st.MaxOpenConnections,
+			Open:              st.OpenConnections,
+			InUse:             st.InUse,
+			Idle:              st.Idle,
+			WaitCount:         st.WaitCount,
+			WaitDuration:      st.WaitDuration.Nanoseconds(),
+			MaxIdleClosed:     st.MaxIdleClosed,
+			MaxIdleTimeClosed: st.MaxIdleTimeClosed,
+			MaxLifetimeClosed: st.MaxLifetimeClosed,
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(list)
+}
@@ -0,0 +1,154 @@
+package timeline
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+func open(t *testing.T, interval time.Duration) (*Recorder, *sql.DB) {
+	t.Helper()
+	s := fakedb.NewScript()
+	s.Query("SELECT").Rows([]string{"n"}, []driver.Value{int64(1)})
+	r := New(interval)
+	now := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	r.now = func() time.Time {
+		defer func() { now = now.Add(time.Millisecond) }()
+		return now
+	}
+	db := sql.OpenDB(r.Wrap(fakedb.NewConnector(s)))
+	db.SetMaxOpenConns(2)
+	return r, db
+}
+
+func TestChart(t *testing.T) {
+	r, db := open(t, 0)
+	ctx := context.Background()
+	r.Start(db)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, err := db.QueryContext(ctx, "SELECT")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows.Close()
+	conn.Close()
+	db.Close()
+	r.Stop() // DB.Closeで停止済みなので記録しない
+
+	var b strings.Builder
+	if err := WriteChart(&b, r.Samples()); err != nil {
+		t.Fatal(err)
+	}
+	want := "" +
+		"elapsed  event       conn  pool  Open  InUse  Idle  WaitCount  WaitDuration  MaxIdleClosed  MaxIdleTimeClosed  MaxLifetimeClosed\n" +
+		"0s       init              [  ]  0     0      0     0          0s            0              0                  0\n" +
+		"1ms      connect     1     [# ]  1     1      0     0          0s            0              0                  0\n" +
+		"2ms      connect     2     [##]  2     2      0     0          0s            0              0                  0\n" +
+		"3ms      query       2     [##]  2     2      0     0          0s            0              0                  0\n" +
+		"4ms      rows-close  2     [##]  2     2      0     0          0s            0              0                  0\n" +
+		"5ms      checkin     2     [##]  2     2      0     0          0s            0              0                  0\n" +
+		"6ms      checkin     1     [#-]  2     1      1     0          0s            0              0                  0\n" +
+		"7ms      close       2     [##]  2     2      0     0          0s            0              0                  0\n" +
+		"8ms      close       1     [# ]  1     1      0     0          0s            0              0                  0\n" +
+		"9ms      final             [  ]  0     0      0     0          0s            0              0                  0\n"
+	if b.String() != want {
+		t.Errorf("chart\n got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestWait(t *testing.T) {
+	r, db := open(t, time.Millisecond)
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+	ctx := context.Background()
+	r.Start(db)
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	done := make(chan error)
+	go func() {
+		_, err := db.ExecContext(ctx, "INSERT")
+		done <- err
+	}()
+	for db.Stats().WaitCount == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	time.Sleep(5 * time.Millisecond) // tickで待ちを記録する
+	conn.Close()
+	if err := <-done; err != nil {
+		t.Fatal(err)
+	}
+	r.Stop()
+
+	samples := r.Samples()
+	ticks := 0
+	for _, s := range samples {
+		if s.Event == EventTick {
+			ticks++
+		}
+	}
+	last := samples[len(samples)-1]
+	if ticks == 0 || last.Event != EventFinal || last.Stats.WaitCount != 1 || last.Stats.WaitDuration == 0 {
+		t.Errorf("ticks = %d, last = %+v", ticks, last)
+	}
+}
+
+func TestWriteJSON(t *testing.T) {
+	var b strings.Builder
+	err := WriteJSON(&b, []Sample{
+		{Elapsed: 1500 * time.Microsecond, Event: "checkin", ConnID: 1, Stats: sql.DBStats{
+			MaxOpenConnections: 2, OpenConnections: 2, InUse: 1, Idle: 1, WaitCount: 3, WaitDuration: time.Millisecond, MaxLifetimeClosed: 1,
+		}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `[
+  {
+    "elapsed": 1500000,
+    "event": "checkin",
+    "conn": 1,
+    "maxOpen": 2,
+    "open": 2,
+    "inUse": 1,
+    "idle": 1,
+    "waitCount": 3,
+    "waitDuration": 1000000,
+    "maxIdleClosed": 0,
+    "maxIdleTimeClosed": 0,
+    "maxLifetimeClosed": 1
+  }
+]
+`
+	if b.String() != want {
+		t.Errorf("json\n got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
+
+func TestChartClosed(t *testing.T) {
+	var b strings.Builder
+	err := WriteChart(&b, []Sample{
+		{Event: EventFinal, Stats: sql.DBStats{MaxIdleClosed: 1, MaxIdleTimeClosed: 2, MaxLifetimeClosed: 3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// ConnMaxIdleTimeでクローズされた数も表示する
+	want := "" +
+		"elapsed  event  conn  pool  Open  InUse  Idle  WaitCount  WaitDuration  MaxIdleClosed  MaxIdleTimeClosed  MaxLifetimeClosed\n" +
+		"0s       final        [ ]   0     0      0     0          0s            1              2                  3\n"
+	if b.String() != want {
+		t.Errorf("chart\n got:\n%s\nwant:\n%s", b.String(), want)
+	}
+}
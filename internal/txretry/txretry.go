@@ -0,0 +1,107 @@
+// Package txretry はシリアライズ失敗やデッドロックで失敗したトランザクションを再実行する
+package txretry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"time"
+
+	"github.com/ystkg/db-examples/internal/dberr"
+)
+
+// Policy は再実行の方針
+type Policy struct {
+	MaxAttempts int           // 最大の実行回数。0の場合は5
+	Budget      time.Duration // 最初の実行からの制限時間。0の場合はctxの期限のみ
+	BaseDelay   time.Duration // 待機時間の基準。0の場合は10ms
+	MaxDelay    time.Duration // 待機時間の上限。0の場合は1s
+}
+
+// ErrExhausted は再実行の回数か制限時間を使い切ったことを表す
+var ErrExhausted = errors.New("txretry: retry budget exhausted")
+
+// sleep はテストで差し替える
+var sleep = func(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
+
+func (p Policy) maxAttempts() int {
+	if p.MaxAttempts <= 0 {
+		return 5
+	}
+	return p.MaxAttempts
+}
+
+// delay はattempt回目の失敗の後の待機時間（full jitter）
+func (p Policy) delay(attempt int) time.Duration {
+	base, maxDelay := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = 10 * time.Millisecond
+	}
+	if maxDelay <= 0 {
+		maxDelay = time.Second
+	}
+	d := maxDelay
+	if attempt < 30 && base<<attempt < maxDelay {
+		d = base << attempt
+	}
+	return rand.N(d) + 1
+}
+
+// Do はトランザクションを開始してfを実行し、コミットする
+// fかコミットが再実行できるエラーで失敗した場合は、ロールバックして待機した後に最初からやり直す
+// fは実行の度に呼ばれるので、トランザクションの外に副作用を残さないようにする
+func Do(ctx context.Context, db *sql.DB, opts *sql.TxOptions, p Policy, f func(tx *sql.Tx) error) error {
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		err := run(ctx, db, opts, f)
+		if err == nil || !Retryable(err) {
+			return err
+		}
+		if attempt >= p.maxAttempts() {
+			return fmt.Errorf("%w: %d attempts: %w", ErrExhausted, attempt, err)
+		}
+		delay := p.delay(attempt)
+		if 0 < p.Budget && p.Budget < time.Since(start)+delay {
+			return fmt.Errorf("%w: %s: %w", ErrExhausted, p.Budget, err)
+		}
+		slog.WarnContext(ctx, "Retry", "attempt", attempt, "delay", delay, "err", err)
+		if err := sleep(ctx, delay); err != nil {
+			return err
+		}
+	}
+}
+
+func run(ctx context.Context, db *sql.DB, opts *sql.TxOptions, f func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if err := tx.Rollback(); err != nil && !errors.Is(err, sql.ErrTxDone) {
+			slog.WarnContext(ctx, "Rollback", "err", err)
+		}
+	}()
+
+	if err := f(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// Retryable はトランザクションを最初からやり直せば成功する可能性があるエラーかどうか
+// dberrのSerializationFailureとDeadlockが該当する
+func Retryable(err error) bool {
+	return dberr.Is(err, dberr.SerializationFailure, dberr.Deadlock)
+}
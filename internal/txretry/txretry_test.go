@@ -0,0 +1,92 @@
+package txretry
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/ystkg/db-examples/internal/fakedb"
+)
+
+func noSleep(t *testing.T) *[]time.Duration {
+	t.Helper()
+	var delays []time.Duration
+	prev := sleep
+	sleep = func(_ context.Context, d time.Duration) error {
+		delays = append(delays, d)
+		return nil
+	}
+	t.Cleanup(func() { sleep = prev })
+	return &delays
+}
+
+func insert(tx *sql.Tx) error {
+	_, err := tx.Exec("INSERT INTO movie (title) VALUES ('A')")
+	return err
+}
+
+func TestDoRetry(t *testing.T) {
+	delays := noSleep(t)
+	s := fakedb.NewScript()
+	s.Commit().Err(&pgconn.PgError{Code: "40001"}).Once()
+	s.Exec("INSERT").Err(&mysql.MySQLError{Number: 1213}).Once()
+	db := fakedb.Open(s)
+	defer db.Close()
+
+	if err := Do(context.Background(), db, nil, Policy{}, insert); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"Begin", "Exec INSERT INTO movie (title) VALUES ('A')", "Rollback",
+		"Begin", "Exec INSERT INTO movie (title) VALUES ('A')", "Commit",
+		"Begin", "Exec INSERT INTO movie (title) VALUES ('A')", "Commit",
+	}
+	if got := s.Trace(); !slices.Equal(got, want) {
+		t.Errorf("trace\n got: %q\nwant: %q", got, want)
+	}
+	if len(*delays) != 2 {
+		t.Errorf("delays = %v", *delays)
+	}
+}
+
+func TestDoNotRetryable(t *testing.T) {
+	noSleep(t)
+	s := fakedb.NewScript()
+	errUnique := &pgconn.PgError{Code: "23505"}
+	s.Exec("INSERT").Err(errUnique)
+	db := fakedb.Open(s)
+	defer db.Close()
+
+	if err := Do(context.Background(), db, nil, Policy{}, insert); !errors.Is(err, errUnique) {
+		t.Fatalf("err = %v", err)
+	}
+	if n := len(s.Trace()); n != 3 {
+		t.Errorf("trace = %q", s.Trace())
+	}
+}
+
+func TestDoExhausted(t *testing.T) {
+	delays := noSleep(t)
+	s := fakedb.NewScript()
+	s.Exec("INSERT").Err(&pgconn.PgError{Code: "40P01"})
+	db := fakedb.Open(s)
+	defer db.Close()
+
+	err := Do(context.Background(), db, nil, Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 2 * time.Millisecond}, insert)
+	if !errors.Is(err, ErrExhausted) || !Retryable(err) {
+		t.Fatalf("err = %v", err)
+	}
+	if len(*delays) != 2 {
+		t.Errorf("delays = %v", *delays)
+	}
+	for _, d := range *delays {
+		if d <= 0 || 2*time.Millisecond < d {
+			t.Errorf("delay = %s", d)
+		}
+	}
+}